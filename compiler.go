@@ -0,0 +1,118 @@
+package coffeemachine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anshal21/coffee-machine/expressions"
+	"github.com/anshal21/coffee-machine/lib/errors"
+	"github.com/anshal21/coffee-machine/lib/models"
+)
+
+const (
+	_predicateNamespace = "Predicate"
+	_constantNamespace  = "Constant"
+)
+
+// compiler compiles the expressions of a rule-set
+// It resolves the references to the predicates e.g Predicate:P1 to the
+// compiled syntax tree of the predicate, every predicate is compiled
+// once and shared by all the expressions referring to it
+// The references to the constants e.g Constant:LIMIT are folded into
+// the expressions, and the expressions are type checked with the types
+// of the variables
+// The functions and the operators of the registry can be used in the
+// expressions
+type compiler struct {
+	predicates map[string]string
+	constants  map[string]expressions.Expression
+	types      map[string]models.DataType
+	registry   *expressions.Registry
+	compiled   map[string]expressions.Expression
+	// resolving is the chain of predicates being compiled, it is used
+	// to detect cyclic references between the predicates
+	resolving []string
+	// failure is the innermost error hit while resolving a reference,
+	// it is reported as is by the outer references
+	failure error
+}
+
+func newCompiler(predicates map[string]string, constants map[string]expressions.Expression,
+	types map[string]models.DataType, registry *expressions.Registry) *compiler {
+	return &compiler{
+		predicates: predicates,
+		constants:  constants,
+		types:      types,
+		registry:   registry,
+		compiled:   make(map[string]expressions.Expression, len(predicates)),
+	}
+}
+
+// compile compiles an expression of the rule-set
+func (c *compiler) compile(expr string) (expressions.Expression, error) {
+	if len(c.resolving) == 0 {
+		c.failure = nil
+	}
+	return expressions.New(expr,
+		expressions.WithReferenceResolver(c.resolve),
+		expressions.WithVariableTypes(c.types),
+		expressions.WithRegistry(c.registry))
+}
+
+// resolve resolves a reference to a predicate or a constant, a predicate
+// is compiled on the first reference
+func (c *compiler) resolve(reference string) (expressions.Expression, error) {
+	expr, err := c.resolveReference(reference)
+	if err != nil {
+		if c.failure == nil {
+			c.failure = err
+		}
+		return nil, c.failure
+	}
+	return expr, nil
+}
+
+func (c *compiler) resolveReference(reference string) (expressions.Expression, error) {
+	parts := strings.SplitN(reference, ":", 2)
+	switch parts[0] {
+	case _predicateNamespace:
+		return c.resolvePredicate(parts[1])
+	case _constantNamespace:
+		constant, ok := c.constants[parts[1]]
+		if !ok {
+			return nil, fmt.Errorf("reference to undefined constant %v", parts[1])
+		}
+		return constant, nil
+	default:
+		return nil, fmt.Errorf("unsupported reference type %v", parts[0])
+	}
+}
+
+func (c *compiler) resolvePredicate(predicateID string) (expressions.Expression, error) {
+	if expr, ok := c.compiled[predicateID]; ok {
+		return expr, nil
+	}
+	predicate, ok := c.predicates[predicateID]
+	if !ok {
+		return nil, fmt.Errorf("reference to invalid predicate %v", predicateID)
+	}
+	for index, id := range c.resolving {
+		if id == predicateID {
+			cycle := append(append([]string{}, c.resolving[index:]...), predicateID)
+			return nil, fmt.Errorf("cyclic predicate reference %v", strings.Join(cycle, " -> "))
+		}
+	}
+
+	c.resolving = append(c.resolving, predicateID)
+	expr, err := c.compile(predicate)
+	c.resolving = c.resolving[:len(c.resolving)-1]
+	if err != nil {
+		if c.failure != nil {
+			return nil, c.failure
+		}
+		return nil, fmt.Errorf("predicate %v is invalid, %v", predicateID, errors.Message(err))
+	}
+
+	c.compiled[predicateID] = expr
+	return expr, nil
+}
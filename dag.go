@@ -0,0 +1,135 @@
+package coffeemachine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anshal21/coffee-machine/lib/errors"
+)
+
+const (
+	_unvisited = iota
+	_visiting
+	_visited
+)
+
+type edgeKey struct {
+	destination *Node
+	when        bool
+}
+
+// validateDAG makes sure that the relations between the rules form a
+// directed acyclic graph
+// It rejects self-loops, duplicate edges and cycles, two relations between
+// the same rules with a different when are not duplicates, the error for a cycle
+// contains the complete path of the cycle e.g R1 -> R2 -> R1
+func validateDAG(nodes []*Node) error {
+	for _, node := range nodes {
+		seen := make(map[edgeKey]struct{})
+		for _, edge := range node.Relations {
+			if edge.Destination == node {
+				return errors.New(ErrInvalidRuleSet, fmt.Errorf("rule %v has a relation to itself", node.Rule.ID))
+			}
+			key := edgeKey{destination: edge.Destination, when: edge.When}
+			if _, ok := seen[key]; ok {
+				return errors.New(ErrInvalidRuleSet, fmt.Errorf("duplicate relation from %v to %v", node.Rule.ID, edge.Destination.Rule.ID))
+			}
+			seen[key] = struct{}{}
+		}
+	}
+
+	state := make(map[*Node]int)
+	path := make([]*Node, 0)
+
+	var visit func(node *Node) error
+	visit = func(node *Node) error {
+		state[node] = _visiting
+		path = append(path, node)
+
+		for _, edge := range node.Relations {
+			switch state[edge.Destination] {
+			case _visiting:
+				return errors.New(ErrInvalidRuleSet, fmt.Errorf("relations contain a cycle %v", cyclePath(path, edge.Destination)))
+			case _unvisited:
+				if err := visit(edge.Destination); err != nil {
+					return err
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[node] = _visited
+		return nil
+	}
+
+	for _, node := range nodes {
+		if state[node] == _unvisited {
+			if err := visit(node); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cyclePath formats the part of the dfs path that starts at the given node
+// and closes the cycle back on it
+func cyclePath(path []*Node, start *Node) string {
+	ids := make([]string, 0, len(path)+1)
+	for index := len(path) - 1; index >= 0; index-- {
+		if path[index] == start {
+			for _, node := range path[index:] {
+				ids = append(ids, node.Rule.ID)
+			}
+			break
+		}
+	}
+	ids = append(ids, start.Rule.ID)
+	return strings.Join(ids, " -> ")
+}
+
+// topologicalOrder computes the execution order for the rule nodes using
+// Kahn's algorithm, the graph is expected to be validated by validateDAG
+// Among the nodes that are ready to be executed at the same time, the one
+// that comes first as per executesBefore is picked, so the order is
+// deterministic
+func topologicalOrder(nodes []*Node, indegree map[*Node]int) []*Node {
+	remaining := make(map[*Node]int, len(indegree))
+	ready := make([]*Node, 0)
+	for _, node := range nodes {
+		remaining[node] = indegree[node]
+		if indegree[node] == 0 {
+			ready = append(ready, node)
+		}
+	}
+
+	order := make([]*Node, 0, len(nodes))
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool {
+			return executesBefore(ready[i], ready[j])
+		})
+		node := ready[0]
+		ready = ready[1:]
+		order = append(order, node)
+
+		for _, edge := range node.Relations {
+			remaining[edge.Destination]--
+			if remaining[edge.Destination] == 0 {
+				ready = append(ready, edge.Destination)
+			}
+		}
+	}
+	return order
+}
+
+// executesBefore tells if node a has to be executed before node b, when
+// both of them are ready to be executed
+// The rule with the smaller order comes first, and among the rules with
+// the same order the one declared earlier in the rule-set comes first
+func executesBefore(a, b *Node) bool {
+	if a.Rule.Order != b.Rule.Order {
+		return a.Rule.Order < b.Rule.Order
+	}
+	return a.position < b.position
+}
@@ -0,0 +1,119 @@
+package coffeemachine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Set of rule-set formats supported out of the box
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Decoder is an interface to decode a rule-set document of some format
+// Decode unmarshals the document into v following the same field names
+// as the JSON schema of the rule-set, it returns a Locator to find the
+// position of the elements in the document, the Locator can be nil if
+// the format doesn't support it
+type Decoder interface {
+	Decode(data []byte, v interface{}) (Locator, error)
+}
+
+// Locator is an interface to find the position of an element in a
+// rule-set document
+// The element is identified by its path from the root of the document
+// e.g ["rules", "R1"] or ["relations", "0"], Locate returns an empty
+// string if the element can't be found
+type Locator interface {
+	Locate(path ...string) string
+}
+
+// NewJSONDecoder is a constructor for the Decoder of JSON rule-sets
+func NewJSONDecoder() Decoder {
+	return &jsonDecoder{}
+}
+
+type jsonDecoder struct{}
+
+func (j *jsonDecoder) Decode(data []byte, v interface{}) (Locator, error) {
+	return nil, json.Unmarshal(data, v)
+}
+
+// NewYAMLDecoder is a constructor for the Decoder of YAML rule-sets
+// The Locator returned by it reports the line and column of the elements
+func NewYAMLDecoder() Decoder {
+	return &yamlDecoder{}
+}
+
+type yamlDecoder struct{}
+
+func (y *yamlDecoder) Decode(data []byte, v interface{}) (Locator, error) {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(data, root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, errEmptyRuleSet
+	}
+	if err := root.Decode(v); err != nil {
+		return nil, err
+	}
+	return &yamlLocator{root: root}, nil
+}
+
+// errEmptyRuleSet is returned for a document without any node e.g an
+// empty or a comment-only YAML document
+var errEmptyRuleSet = fmt.Errorf("empty rule-set")
+
+type yamlLocator struct {
+	root *yaml.Node
+}
+
+func (y *yamlLocator) Locate(path ...string) string {
+	curr := y.root
+	if curr.Kind == yaml.DocumentNode && len(curr.Content) > 0 {
+		curr = curr.Content[0]
+	}
+
+	for depth, key := range path {
+		var next *yaml.Node
+		switch curr.Kind {
+		case yaml.MappingNode:
+			for index := 0; index+1 < len(curr.Content); index += 2 {
+				if curr.Content[index].Value == key {
+					// the position of a mapping entry is the position of its key
+					if depth == len(path)-1 {
+						next = curr.Content[index]
+					} else {
+						next = curr.Content[index+1]
+					}
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			index, err := strconv.Atoi(key)
+			if err == nil && index >= 0 && index < len(curr.Content) {
+				next = curr.Content[index]
+			}
+		}
+		if next == nil {
+			return ""
+		}
+		curr = next
+	}
+	return fmt.Sprintf("line %v, column %v", curr.Line, curr.Column)
+}
+
+// sniffFormat guesses the format of a rule-set document, a document
+// starting with '{' is treated as JSON and anything else as YAML
+func sniffFormat(data []byte) string {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return FormatJSON
+	}
+	return FormatYAML
+}
@@ -0,0 +1,127 @@
+package coffeemachine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ruleSetDefinition is the schema of a rule-set document, the field names
+// are same for all the formats
+type ruleSetDefinition struct {
+	ID         string                         `json:"id" yaml:"id"`
+	Constants  map[string]interface{}         `json:"constants" yaml:"constants"`
+	Schema     map[string]*variableDefinition `json:"schema" yaml:"schema"`
+	Predicates map[string]string              `json:"predicates" yaml:"predicates"`
+	Rules      ruleDefinitions                `json:"rules" yaml:"rules"`
+	Relations  []relationDefinition           `json:"relations" yaml:"relations"`
+}
+
+// ruleDefinitions holds the rules of a rule-set in the order of declaration
+// It is decoded from an object keyed by the rule id, unlike a go map it keeps
+// the order of the keys and the keys that are repeated
+type ruleDefinitions []*ruleDefinition
+
+type ruleDefinition struct {
+	ID          string               `json:"-" yaml:"-"`
+	Description string               `json:"description" yaml:"description"`
+	Tags        []string             `json:"tags" yaml:"tags"`
+	Enabled     *bool                `json:"enabled" yaml:"enabled"`
+	ValidFrom   string               `json:"valid_from" yaml:"valid_from"`
+	ValidUntil  string               `json:"valid_until" yaml:"valid_until"`
+	Order       int                  `json:"order" yaml:"order"`
+	Priority    int                  `json:"priority" yaml:"priority"`
+	Join        string               `json:"join" yaml:"join"`
+	JoinCount   int                  `json:"join_count" yaml:"join_count"`
+	Predicate   string               `json:"predicate" yaml:"predicate"`
+	PostEvals   []postEvalDefinition `json:"post_evals" yaml:"post_evals"`
+	OnFalse     []postEvalDefinition `json:"on_false" yaml:"on_false"`
+}
+
+// postEvals returns the post-evals of the rule for the field, post_evals
+// or on_false
+func (r *ruleDefinition) postEvals(field string) []postEvalDefinition {
+	if field == "on_false" {
+		return r.OnFalse
+	}
+	return r.PostEvals
+}
+
+type postEvalDefinition struct {
+	ID    string `json:"id" yaml:"id"`
+	Type  string `json:"type" yaml:"type"`
+	Value string `json:"value" yaml:"value"`
+	Echo  bool   `json:"echo" yaml:"echo"`
+}
+
+type variableDefinition struct {
+	Type     string      `json:"type" yaml:"type"`
+	Required bool        `json:"required" yaml:"required"`
+	Default  interface{} `json:"default" yaml:"default"`
+}
+
+type relationDefinition struct {
+	From          string `json:"from" yaml:"from"`
+	To            string `json:"to" yaml:"to"`
+	ForwardOutput bool   `json:"forward_output" yaml:"forward_output"`
+	When          *bool  `json:"when" yaml:"when"`
+	Condition     string `json:"condition" yaml:"condition"`
+}
+
+// UnmarshalJSON decodes the rules object key by key to keep the order
+// of declaration
+func (r *ruleDefinitions) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		*r = nil
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("rules must be an object keyed by the rule id")
+	}
+
+	rules := make(ruleDefinitions, 0)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		rule := &ruleDefinition{
+			ID: token.(string),
+		}
+		if err := decoder.Decode(rule); err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+	*r = rules
+	return nil
+}
+
+// UnmarshalYAML decodes the rules mapping key by key to keep the order
+// of declaration
+func (r *ruleDefinitions) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("rules must be a mapping keyed by the rule id at line %v, column %v", value.Line, value.Column)
+	}
+
+	rules := make(ruleDefinitions, 0, len(value.Content)/2)
+	for index := 0; index+1 < len(value.Content); index += 2 {
+		rule := &ruleDefinition{
+			ID: value.Content[index].Value,
+		}
+		if err := value.Content[index+1].Decode(rule); err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+	*r = rules
+	return nil
+}
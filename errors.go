@@ -8,4 +8,12 @@ import (
 const (
 	// ErrInvalidRuleSet represents some error in the provided ruleset
 	ErrInvalidRuleSet errors.ErrCode = "ErrInvalidRuleSet"
+	// ErrRuleSetNotFound represents a failure to locate or read the rule-set
+	// from its source
+	ErrRuleSetNotFound errors.ErrCode = "ErrRuleSetNotFound"
+	// ErrInvalidRequest represents some error in the parameters of a run
+	ErrInvalidRequest errors.ErrCode = "ErrInvalidRequest"
+	// ErrInvalidVariables represents variables of a run that don't conform
+	// to the schema of the rule-set, the error has a detail for every problem
+	ErrInvalidVariables errors.ErrCode = "ErrInvalidVariables"
 )
@@ -11,84 +11,301 @@ import (
 // Evaluator is an interface to evaluate a rule-graph against
 // given set of parameter values
 // It exposes and Evaluate method that evaluates the rule-graph
+// and an EvaluateStream method that hands over the outputs of
+// the rules as soon as they are evaluated
 type Evaluator interface {
 	Evaluate(req *RuleEngineRequest) (*RuleEngineResponse, error)
+	EvaluateStream(req *RuleEngineRequest, handler OutputHandler) error
 }
 
+// OutputHandler is a callback to process the output of a rule, the
+// evaluation is stopped if the handler returns an error and the same
+// error is returned to the caller
+type OutputHandler func(ruleOutput *RuleOutput) error
+
 // NewEvaluator is a constructor for Evaluator
 // It takes a rule-graph as an input and returns an instance of Evaluator
-func NewEvaluator(ruleGraph *RuleGraph) Evaluator {
+// The clock used to check the validity of the rules can be set using WithClock
+func NewEvaluator(ruleGraph *RuleGraph, options ...Option) Evaluator {
 	return &evaluator{
 		ruleGraph: ruleGraph,
+		config:    newConfig(options...),
 	}
 }
 
 type evaluator struct {
 	ruleGraph *RuleGraph
+	config    *config
 }
 
 // Evaluate method evaluates the rule-graph against the rule-graph
-// It traverses the graph in the dependency order and evaluates the nodes
-// for the expressions associated
-// It early stops, i.e doesn't evaluate a node if some of it dependecy evaluates to false
+// It walks the pre-computed execution order of the graph and evaluates
+// every node at most once
+// A node is evaluated only if it doesn't depend on any other rule or if
+// its relations are followed as per the join mode of the rule, a relation
+// is followed if the parent rule has evaluated to the value of the when of
+// the relation, true by default, and the condition of the relation holds
+// The rules that are disabled or out of their validity window are skipped
+// as if they were never reached
+// If the request filters the rules, only the selected rules and their
+// ancestors are evaluated and only the outputs of the selected rules are
+// returned
+// The variables are validated against the schema of the rule-set, if any,
+// before the evaluation and the defaults are applied to the missing ones
 func (e *evaluator) Evaluate(req *RuleEngineRequest) (*RuleEngineResponse, error) {
 	response := &RuleEngineResponse{}
 
-	outCh := make(chan *RuleOutput, 100)
-	// TODO: this can be improved by pre-computing the execution order using topo-sort
-	err := e.dfs(req, e.ruleGraph.Root, outCh, &evaluationStats{})
+	stats, err := e.evaluate(req, func(ruleOutput *RuleOutput) error {
+		response.Outputs = append(response.Outputs, ruleOutput)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	close(outCh)
 
-	for ruleOutput := range outCh {
-		response.Outputs = append(response.Outputs, ruleOutput)
+	stats.populate(req, response)
+	return response, nil
+}
+
+// EvaluateStream method evaluates the rule-graph in the same way as
+// Evaluate, but instead of collecting the outputs in a response it calls
+// the handler for every rule output, in the order of execution
+// The outputs are handed over only at the end of the run if the request uses
+// a strategy other than StrategyAllMatches
+func (e *evaluator) EvaluateStream(req *RuleEngineRequest, handler OutputHandler) error {
+	_, err := e.evaluate(req, handler)
+	return err
+}
+
+func (e *evaluator) evaluate(req *RuleEngineRequest, handler OutputHandler) (*evaluationStats, error) {
+	selector, err := newOutputSelector(req, handler)
+	if err != nil {
+		return nil, err
 	}
+	variables, err := validateVariables(e.ruleGraph.Schema, req.Variables)
+	if err != nil {
+		return nil, err
+	}
+	validated := *req
+	validated.Variables = variables
+	req = &validated
 
-	return response, nil
+	state := newEvaluationState(len(e.ruleGraph.ExecutionOrder))
+	stats := &evaluationStats{}
+	now := e.config.clock()
+	selection := newRuleSelection(req, e.ruleGraph)
+
+	for _, node := range e.ruleGraph.ExecutionOrder {
+		if !selection.isRequired(node) || !node.Rule.IsActive(now) || !state.isReachable(node) {
+			continue
+		}
+		variables := state.variables(req, node)
+		matched, ruleOutput, err := e.evaluateNode(node, variables, stats)
+		if err != nil {
+			return nil, err
+		}
+		state.record(node, matched, ruleOutput)
+		if err := state.followRelations(node, variables); err != nil {
+			return nil, err
+		}
+		if ruleOutput != nil && selection.isSelected(node) {
+			if err := selector.add(node, ruleOutput); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := selector.flush(); err != nil {
+		return nil, err
+	}
+	return stats, nil
 }
 
 type evaluationStats struct {
-	evaluated      int
-	evaluatedTrue  int
-	evaluatedRules []string
+	evaluated           int
+	evaluatedTrue       int
+	evaluatedRules      []string
+	evaluatedFalseRules []string
+}
+
+// record updates the stats with the result of a rule evaluation
+func (s *evaluationStats) record(ruleID string, res bool) {
+	s.evaluated++
+	s.evaluatedRules = append(s.evaluatedRules, ruleID)
+	if res {
+		s.evaluatedTrue++
+		return
+	}
+	s.evaluatedFalseRules = append(s.evaluatedFalseRules, ruleID)
+}
+
+// populate fills the stats in the response as per the output
+// criteria of the request
+func (s *evaluationStats) populate(req *RuleEngineRequest, response *RuleEngineResponse) {
+	if req.EvaluatedCount {
+		response.RulesEvaluated = s.evaluated
+	}
+	if req.EvaluatedTrueCount {
+		response.RulesEvaluatedTrue = s.evaluatedTrue
+	}
+	if req.EvaluatedRules {
+		response.EvaluatedRules = s.evaluatedRules
+		response.EvaluatedFalseRules = s.evaluatedFalseRules
+	}
+}
+
+// evaluationState holds the results of the nodes evaluated so far
+// in a single run of the evaluator
+type evaluationState struct {
+	matched  map[*Node]bool
+	outputs  map[*Node]*RuleOutput
+	followed map[*Edge]bool
+}
+
+func newEvaluationState(size int) *evaluationState {
+	return &evaluationState{
+		matched:  make(map[*Node]bool, size),
+		outputs:  make(map[*Node]*RuleOutput, size),
+		followed: make(map[*Edge]bool, size),
+	}
 }
 
-func (e *evaluator) dfs(req *RuleEngineRequest, node *Node, outCh chan<- *RuleOutput, stats *evaluationStats) error {
+// record stores the result of a node evaluation, the output is nil
+// if the rule doesn't have post-evals for the result
+func (s *evaluationState) record(node *Node, matched bool, ruleOutput *RuleOutput) {
+	s.matched[node] = matched
+	if ruleOutput != nil {
+		s.outputs[node] = ruleOutput
+	}
+}
+
+// followRelations decides which of the relations of an evaluated node are
+// followed, a relation is followed if the result of the rule matches the
+// when of the relation and the condition of the relation, if any, holds
+// The condition is evaluated against the variables of the node along with
+// the outputs of the node if the relation forwards them
+func (s *evaluationState) followRelations(node *Node, variables map[string]interface{}) error {
+	for _, edge := range node.Relations {
+		if s.matched[node] != edge.When {
+			continue
+		}
+		if edge.Condition == nil {
+			s.followed[edge] = true
+			continue
+		}
+
+		conditionVariables := variables
+		if ruleOutput, ok := s.outputs[node]; ok && edge.ForwardOutput {
+			conditionVariables = withForwardedOutput(variables, ruleOutput)
+		}
+		res, err := edge.Condition.Evaluate(&expressions.EvaluationRequest{
+			Variables: conditionVariables,
+		})
+		if err != nil {
+			return err
+		}
+		if res.Type != models.DataTypeBool {
+			return fmt.Errorf("relation from %v to %v, does not have a boolean condition", node.Rule.ID, edge.Destination.Rule.ID)
+		}
+		s.followed[edge] = *res.Value.Bool
+	}
+	return nil
+}
+
+// isReachable tells if a node has to be evaluated, given the results
+// of the nodes that have been evaluated so far
+// The number of followed relations needed depends on the join mode of
+// the rule, a rule without relations is always evaluated
+func (s *evaluationState) isReachable(node *Node) bool {
+	if len(node.Incoming) == 0 {
+		return true
+	}
+
+	followed := 0
+	for _, edge := range node.Incoming {
+		if s.followed[edge] {
+			followed++
+		}
+	}
+
+	switch node.Rule.Join {
+	case JoinAll:
+		return followed == len(node.Incoming)
+	case JoinNOfM:
+		return followed >= node.Rule.JoinCount
+	default:
+		return followed > 0
+	}
+}
+
+// variables returns the variable values a node is evaluated with
+// These are the request variables along with the post-evals of the parent
+// rules connected through a followed forward_output relation
+func (s *evaluationState) variables(req *RuleEngineRequest, node *Node) map[string]interface{} {
+	variables := req.Variables
+	for _, edge := range node.Incoming {
+		ruleOutput, ok := s.outputs[edge.Source]
+		if !edge.ForwardOutput || !ok || !s.followed[edge] {
+			continue
+		}
+		variables = withForwardedOutput(variables, ruleOutput)
+	}
+	return variables
+}
+
+// withForwardedOutput returns a copy of the variables along with the
+// post-evals of the rule output, the forwarded values are namespaced by
+// the rule id e.g R1.output_1 and take precedence over a variable with
+// the same name
+func withForwardedOutput(variables map[string]interface{}, ruleOutput *RuleOutput) map[string]interface{} {
+	res := make(map[string]interface{}, len(variables)+len(ruleOutput.PostEvals))
+	for key, val := range variables {
+		res[key] = val
+	}
+	for _, postEval := range ruleOutput.PostEvals {
+		res[ruleOutput.ID+"."+postEval.ID] = postEval.Value.Interface()
+	}
+	return res
+}
+
+// evaluateNode evaluates the predicate of the rule and the post-evals
+// for the result, i.e the post_evals if the predicate is true and the
+// on_false post-evals otherwise
+func (e *evaluator) evaluateNode(node *Node, variables map[string]interface{}, stats *evaluationStats) (bool, *RuleOutput, error) {
 	res, err := node.Rule.Predicate.Evaluate(&expressions.EvaluationRequest{
-		Variables: req.Variables,
+		Variables: variables,
 	})
 
 	if err != nil {
-		return err
+		return false, nil, err
 	}
 
 	if res.Type != models.DataTypeBool {
-		return fmt.Errorf("rule %v, does not have a boolean expression", node.Rule.ID)
+		return false, nil, fmt.Errorf("rule %v, does not have a boolean expression", node.Rule.ID)
 	}
 
-	if *res.Value.Bool {
-		if node.Rule.ID != _rootNodeID {
-			postEvals, err := e.evaluatePostEvals(req, node.Rule.PostEvals)
-			if err != nil {
-				return err
-			}
-			postEvals.ID = node.Rule.ID
-			outCh <- postEvals
-		}
+	matched := *res.Value.Bool
+	stats.record(node.Rule.ID, matched)
 
-		for _, edge := range node.Relations {
-			err = e.dfs(req, edge.Destination, outCh, stats)
-			if err != nil {
-				return err
-			}
+	postEvals := node.Rule.PostEvals
+	if !matched {
+		if len(node.Rule.OnFalse) == 0 {
+			return false, nil, nil
 		}
+		postEvals = node.Rule.OnFalse
 	}
-	return nil
+
+	ruleOutput, err := e.evaluatePostEvals(variables, postEvals)
+	if err != nil {
+		return false, nil, err
+	}
+	ruleOutput.ID = node.Rule.ID
+	ruleOutput.OnFalse = !matched
+
+	return matched, ruleOutput, nil
 }
 
-func (e *evaluator) evaluatePostEvals(req *RuleEngineRequest, postEvals []*RulePostEval) (*RuleOutput, error) {
+func (e *evaluator) evaluatePostEvals(variables map[string]interface{}, postEvals []*RulePostEval) (*RuleOutput, error) {
 
 	ruleOutput := &RuleOutput{
 		PostEvals: make([]*EvaluationOutput, 0, len(postEvals)),
@@ -98,7 +315,7 @@ func (e *evaluator) evaluatePostEvals(req *RuleEngineRequest, postEvals []*RuleP
 		switch postEval.Type {
 		case OutputTypeExpression:
 			res, err := postEval.Evaluable.Evaluate(&expressions.EvaluationRequest{
-				Variables: req.Variables,
+				Variables: variables,
 			})
 			if err != nil {
 				return nil, err
@@ -0,0 +1,92 @@
+package expressions
+
+import (
+	"fmt"
+
+	"github.com/anshal21/coffee-machine/lib"
+	"github.com/anshal21/coffee-machine/lib/errors"
+	"github.com/anshal21/coffee-machine/lib/models"
+)
+
+// NewConstant is a constructor to instantiate an Expression that
+// always evaluates to the given value
+// The value can be a number, a string, a bool or a list of them
+// A reference resolved to a constant is folded in the referring
+// expression i.e it is replaced by the value itself
+func NewConstant(value interface{}) (Expression, error) {
+	token, err := literalToken(value)
+	if err != nil {
+		return nil, err
+	}
+	root := &node{
+		Token: token,
+	}
+	dataType, _ := inferType(root, nil)
+	return &expression{
+		infix: fmt.Sprintf("%v", value),
+		abstractSyntaxtTree: &syntaxTree{
+			Root: root,
+		},
+		evaluator: NewEvaluator(),
+		dataType:  dataType,
+	}, nil
+}
+
+func literalToken(value interface{}) (*Token, error) {
+	switch value.(type) {
+	case string:
+		return &Token{Type: String, Value: value}, nil
+	case float64:
+		return &Token{Type: Number, Value: value}, nil
+	case int:
+		return &Token{Type: Number, Value: float64(value.(int))}, nil
+	case bool:
+		return &Token{Type: Bool, Value: value}, nil
+	case []interface{}, []string, []float64, []int:
+		list, err := listValue(value)
+		if err != nil {
+			return nil, err
+		}
+		return &Token{Type: List, Value: list}, nil
+	}
+	return nil, errors.New(ErrInvalidExpression, fmt.Errorf("unsupported constant type %T", value))
+}
+
+// listValue converts a list to a list of values, the elements
+// of the list should be numbers, strings or bools
+func listValue(list interface{}) ([]models.Value, error) {
+	var elements []interface{}
+	switch list.(type) {
+	case []interface{}:
+		elements = list.([]interface{})
+	case []string:
+		for _, element := range list.([]string) {
+			elements = append(elements, element)
+		}
+	case []float64:
+		for _, element := range list.([]float64) {
+			elements = append(elements, element)
+		}
+	case []int:
+		for _, element := range list.([]int) {
+			elements = append(elements, element)
+		}
+	}
+
+	values := make([]models.Value, 0, len(elements))
+	for _, element := range elements {
+		switch element.(type) {
+		case string:
+			values = append(values, models.Value{String: lib.StrPtr(element.(string))})
+		case float64:
+			values = append(values, models.Value{Number: lib.Float64Ptr(element.(float64))})
+		case int:
+			values = append(values, models.Value{Number: lib.Float64Ptr(float64(element.(int)))})
+		case bool:
+			values = append(values, models.Value{Bool: lib.BoolPtr(element.(bool))})
+		default:
+			return nil, errors.New(ErrInvalidExpression, fmt.Errorf("unsupported list element %v of type %T", element, element))
+		}
+	}
+	return values, nil
+}
@@ -10,4 +10,8 @@ const (
 	ErrMissingVariableValue  errors.ErrCode = "MissingVariableValue"
 	ErrIncompatibleOperation errors.ErrCode = "IncompatibleOperation"
 	ErrUnsupportedOperation  errors.ErrCode = "UnsupportedOperation"
+	ErrInvalidReference      errors.ErrCode = "InvalidReference"
+	ErrDivisionByZero        errors.ErrCode = "DivisionByZero"
+	ErrInvalidArgument       errors.ErrCode = "InvalidArgument"
+	ErrInvalidRegistration   errors.ErrCode = "InvalidRegistration"
 )
@@ -1,57 +1,136 @@
 package expressions
 
+import (
+	"fmt"
+
+	"github.com/anshal21/coffee-machine/lib/errors"
+	"github.com/anshal21/coffee-machine/lib/models"
+)
+
 // Expression is an interface to represent an expression
 // It exposes Evaluate method to evaluate an expression
 // and a Visualise method to display the execution plan
+// Type is the type the expression evaluates to, as inferred at the time
+// of creation, it is DataTypeUnknown if it depends on an untyped variable
 type Expression interface {
 	Evaluate(request *EvaluationRequest) (*EvaluationResponse, error)
 	Visualise() error
+	Type() models.DataType
 }
 
 type expression struct {
 	infix               string
 	abstractSyntaxtTree *syntaxTree
 	evaluator           Evaluator
+	dataType            models.DataType
 }
 
 // New is a constructor to instantiate a new Expression
 // example usage:
 // expr, err := New("a > b")
-func New(expr string) (Expression, error) {
-	lexer := NewLexer()
+// The references in the expression e.g Predicate:P1 are resolved
+// at the time of creation using the resolver set with WithReferenceResolver
+// and the expression is type checked with the types of the variables
+// set with WithVariableTypes
+// The functions and the operators of the registry set with WithRegistry
+// can be used in the expression
+func New(expr string, options ...Option) (Expression, error) {
+	c := &config{}
+	for _, option := range options {
+		option(c)
+	}
+
+	lexer := newLexer(c.udfs, c.registry)
 	tokens, err := lexer.Lex(expr)
 	if err != nil {
 		return nil, err
 	}
-	parser := NewParser()
+	parser := &parser{registry: c.registry}
 	ast, err := parser.Parse(tokens)
 	if err != nil {
 		return nil, err
 	}
+	err = resolveReferences(ast.Root, c.resolver)
+	if err != nil {
+		return nil, err
+	}
+	err = bindFunctions(ast.Root, c.registry)
+	if err != nil {
+		return nil, err
+	}
+	dataType, err := inferType(ast.Root, c.variableTypes)
+	if err != nil {
+		return nil, err
+	}
 
 	return &expression{
 		infix:               expr,
 		abstractSyntaxtTree: ast,
-		evaluator:           NewEvaluator(),
+		evaluator:           NewEvaluatorWithUDFs(c.udfs...),
+		dataType:            dataType,
 	}, nil
 }
 
+// NewExpressionsWithUDFs is a constructor to instantiate a new Expression
+// with user defined operators
 func NewExpressionsWithUDFs(expr string, ops ...UDF) (Expression, error) {
-	lexer := NewLexerWithUDFs(ops...)
-	tokens, err := lexer.Lex(expr)
-	if err != nil {
-		return nil, err
+	return New(expr, WithUDFs(ops...))
+}
+
+// resolveReferences links every reference node of the tree to the root
+// of the syntax tree of the referenced expression, the sub-tree is shared
+// by all the expressions referring to it
+// A reference to a literal e.g a constant is replaced by the literal
+func resolveReferences(curr *node, resolver ReferenceResolver) error {
+	if curr == nil {
+		return nil
 	}
-	parser := NewParser()
-	ast, err := parser.Parse(tokens)
+	if curr.Token.Type == Reference {
+		if resolver == nil {
+			return errors.New(ErrInvalidReference,
+				fmt.Errorf("no resolver for reference %v at position %v", curr.Token.Value, curr.Token.Index))
+		}
+		referred, err := resolver(curr.Token.Value.(string))
+		if err != nil {
+			return errors.New(ErrInvalidReference,
+				fmt.Errorf("invalid reference %v at position %v, %v", curr.Token.Value, curr.Token.Index, errors.Message(err)))
+		}
+		expr, ok := referred.(*expression)
+		if !ok {
+			return errors.New(ErrInvalidReference,
+				fmt.Errorf("reference %v at position %v is not resolved to a compiled expression", curr.Token.Value, curr.Token.Index))
+		}
+		target := expr.abstractSyntaxtTree.Root
+		if isLiteral(target.Token) {
+			curr.Token = &Token{
+				Type:  target.Token.Type,
+				Value: target.Token.Value,
+				Index: curr.Token.Index,
+			}
+			return nil
+		}
+		curr.Target = target
+		return nil
+	}
+
+	for _, arg := range curr.Args {
+		if err := resolveReferences(arg, resolver); err != nil {
+			return err
+		}
+	}
+	err := resolveReferences(curr.LeftChild, resolver)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return &expression{
-		infix:               expr,
-		abstractSyntaxtTree: ast,
-		evaluator:           NewEvaluatorWithUDFs(ops...),
-	}, nil
+	return resolveReferences(curr.RightChild, resolver)
+}
+
+func isLiteral(token *Token) bool {
+	switch token.Type {
+	case String, Number, Bool, List:
+		return true
+	}
+	return false
 }
 
 func (e *expression) Evaluate(request *EvaluationRequest) (*EvaluationResponse, error) {
@@ -65,6 +144,10 @@ func (e *expression) Evaluate(request *EvaluationRequest) (*EvaluationResponse,
 	}, nil
 }
 
+func (e *expression) Type() models.DataType {
+	return e.dataType
+}
+
 func (e *expression) Visualise() error {
 	e.abstractSyntaxtTree.Print()
 	return nil
@@ -0,0 +1,230 @@
+package expressions
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/anshal21/coffee-machine/lib"
+	"github.com/anshal21/coffee-machine/lib/errors"
+	"github.com/anshal21/coffee-machine/lib/models"
+)
+
+// function is a function that can be called in an expression e.g max(a, b)
+// The arguments are checked against the params, the trailing optional params
+// can be omitted and the last param can be repeated if the function is variadic
+type function struct {
+	name     string
+	params   []models.DataType
+	optional int
+	variadic bool
+	result   models.DataType
+	call     func(args []models.Value) (models.Value, error)
+}
+
+var (
+	// _builtinFunctions is the library of functions available to every expression
+	_builtinFunctions = functionsByName(
+		mathFunction("abs", 1, 0, false, func(args []float64) (float64, error) {
+			return math.Abs(args[0]), nil
+		}),
+		mathFunction("ceil", 1, 0, false, func(args []float64) (float64, error) {
+			return math.Ceil(args[0]), nil
+		}),
+		mathFunction("floor", 1, 0, false, func(args []float64) (float64, error) {
+			return math.Floor(args[0]), nil
+		}),
+		mathFunction("round", 2, 1, false, round),
+		mathFunction("max", 2, 0, true, func(args []float64) (float64, error) {
+			res := args[0]
+			for _, arg := range args[1:] {
+				res = math.Max(res, arg)
+			}
+			return res, nil
+		}),
+		mathFunction("min", 2, 0, true, func(args []float64) (float64, error) {
+			res := args[0]
+			for _, arg := range args[1:] {
+				res = math.Min(res, arg)
+			}
+			return res, nil
+		}),
+		mathFunction("sqrt", 1, 0, false, func(args []float64) (float64, error) {
+			if args[0] < 0 {
+				return 0, invalidArgumentError("sqrt of negative number %v", args[0])
+			}
+			return math.Sqrt(args[0]), nil
+		}),
+		mathFunction("pow", 2, 0, false, func(args []float64) (float64, error) {
+			return math.Pow(args[0], args[1]), nil
+		}),
+		mathFunction("log", 1, 0, false, func(args []float64) (float64, error) {
+			if args[0] <= 0 {
+				return 0, invalidArgumentError("log of non-positive number %v", args[0])
+			}
+			return math.Log(args[0]), nil
+		}),
+	)
+)
+
+// mathFunction builds a function of numbers that returns a number
+func mathFunction(name string, params, optional int, variadic bool, fn func(args []float64) (float64, error)) *function {
+	paramTypes := make([]models.DataType, params)
+	for index := range paramTypes {
+		paramTypes[index] = models.DataTypeNumber
+	}
+	return &function{
+		name:     name,
+		params:   paramTypes,
+		optional: optional,
+		variadic: variadic,
+		result:   models.DataTypeNumber,
+		call: func(args []models.Value) (models.Value, error) {
+			numbers := make([]float64, 0, len(args))
+			for _, arg := range args {
+				numbers = append(numbers, *arg.Number)
+			}
+			res, err := fn(numbers)
+			if err != nil {
+				return models.Value{}, err
+			}
+			return models.Value{Number: lib.Float64Ptr(res)}, nil
+		},
+	}
+}
+
+// round rounds a number half away from zero, to the given number
+// of decimal places, 0 by default
+func round(args []float64) (float64, error) {
+	if len(args) == 1 {
+		return math.Round(args[0]), nil
+	}
+	if args[1] != math.Trunc(args[1]) || args[1] < 0 {
+		return 0, invalidArgumentError("round to %v decimal places", args[1])
+	}
+	scale := math.Pow(10, args[1])
+	return math.Round(args[0]*scale) / scale, nil
+}
+
+func functionsByName(functions ...*function) map[string]*function {
+	byName := make(map[string]*function, len(functions))
+	for _, fn := range functions {
+		byName[fn.name] = fn
+	}
+	return byName
+}
+
+// paramType returns the type of the parameter at the index
+func (f *function) paramType(index int) models.DataType {
+	if index >= len(f.params) {
+		return f.params[len(f.params)-1]
+	}
+	return f.params[index]
+}
+
+// checkArity checks the number of arguments of a call to the function
+func (f *function) checkArity(call *Token, argCount int) error {
+	minArgs, maxArgs := len(f.params)-f.optional, len(f.params)
+	var expected string
+	switch {
+	case f.variadic:
+		if argCount >= minArgs {
+			return nil
+		}
+		expected = fmt.Sprintf("at least %v", minArgs)
+	case argCount >= minArgs && argCount <= maxArgs:
+		return nil
+	case minArgs == maxArgs:
+		expected = fmt.Sprintf("%v", minArgs)
+	default:
+		expected = fmt.Sprintf("%v to %v", minArgs, maxArgs)
+	}
+	return errors.New(ErrInvalidExpression, fmt.Errorf("function %v expects %v arguments, found %v at position %v",
+		call.Value, expected, argCount, call.Index))
+}
+
+// bindFunctions links every function call in the tree to the function
+// it calls, the number of arguments of the calls are checked on the way
+// The operators defined in the registry are linked to their function too
+func bindFunctions(curr *node, registry *Registry) error {
+	if curr == nil {
+		return nil
+	}
+	if curr.Token.Type == Operator {
+		if op, ok := registry.operator(curr.Token.Value.(string)); ok {
+			curr.Function = op.function
+		}
+	}
+	if curr.Token.Type == Function {
+		fn, ok := registry.function(curr.Token.Value.(string))
+		if !ok {
+			return errors.New(ErrInvalidExpression,
+				fmt.Errorf("unknown function %v at position %v", curr.Token.Value, curr.Token.Index))
+		}
+		if err := fn.checkArity(curr.Token, len(curr.Args)); err != nil {
+			return err
+		}
+		curr.Function = fn
+	}
+	for _, arg := range curr.Args {
+		if err := bindFunctions(arg, registry); err != nil {
+			return err
+		}
+	}
+	if err := bindFunctions(curr.LeftChild, registry); err != nil {
+		return err
+	}
+	return bindFunctions(curr.RightChild, registry)
+}
+
+// operands returns the nodes the function of a node is called with, the
+// arguments of a function call or the operands of a registered operator
+func operands(curr *node) []*node {
+	if curr.Token.Type == Function {
+		return curr.Args
+	}
+	return []*node{curr.LeftChild, curr.RightChild}
+}
+
+// checkArgumentTypes checks the types of the operands of a node against
+// the params of its function, a param of unknown type accepts any type
+func checkArgumentTypes(curr *node, types []models.DataType) error {
+	for index, argType := range types {
+		param := curr.Function.paramType(index)
+		if param == models.DataTypeUnknown || accepts(param, argType) {
+			continue
+		}
+		if curr.Token.Type == Operator {
+			return errors.New(ErrIncompatibleOperation, fmt.Errorf("cannot apply '%v' operation on type '%v' and '%v' at position %v",
+				curr.Token.Value, types[0], types[1], curr.Token.Index))
+		}
+		return argumentTypeError(curr.Token, curr.Function, index, argType)
+	}
+	return nil
+}
+
+// holds tells if the value is of the type, a value without a number, a
+// string or a bool is an empty list
+func holds(value models.Value, dataType models.DataType) bool {
+	switch dataType {
+	case models.DataTypeNumber:
+		return value.Number != nil
+	case models.DataTypeString:
+		return value.String != nil
+	case models.DataTypeBool:
+		return value.Bool != nil
+	case models.DataTypeList:
+		return value.Number == nil && value.String == nil && value.Bool == nil
+	}
+	return false
+}
+
+// argumentTypeError reports an argument of a function call that is not
+// of the type of the parameter
+func argumentTypeError(call *Token, fn *function, index int, found models.DataType) error {
+	return errors.New(ErrIncompatibleOperation, fmt.Errorf("function %v expects %v for argument %v, found %v at position %v",
+		fn.name, fn.paramType(index), index+1, found, call.Index))
+}
+
+func invalidArgumentError(format string, args ...interface{}) *errors.Error {
+	return errors.New(ErrInvalidArgument, fmt.Errorf(format, args...))
+}
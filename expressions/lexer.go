@@ -10,6 +10,8 @@ import (
 
 var (
 	_VariableRegex        *regexp.Regexp
+	_ReferenceRegex       *regexp.Regexp
+	_FunctionRegex        *regexp.Regexp
 	_DecimalRegex         *regexp.Regexp
 	_ValidGlobalOperators = map[string]struct{}{
 		"<":  {},
@@ -17,18 +19,32 @@ var (
 		">=": {},
 		"<=": {},
 		"==": {},
+		"!=": {},
 		"+":  {},
 		"-":  {},
 		"/":  {},
+		"//": {},
+		"%":  {},
 		"*":  {},
 		"^":  {},
 		"||": {},
 		"&&": {},
+		"in": {},
+	}
+	_ValidUnaryOperators = map[rune]struct{}{
+		'!': {},
+		'-': {},
+		'+': {},
 	}
 )
 
 func init() {
-	_VariableRegex, _ = regexp.Compile("^[a-zA-Z_][a-zA-Z_0-9]*$")
+	// variables can be namespaced with dots e.g R1.output_1
+	_VariableRegex, _ = regexp.Compile("^[a-zA-Z_][a-zA-Z_0-9]*(\\.[a-zA-Z_][a-zA-Z_0-9]*)*$")
+	// references are namespaced names e.g Predicate:P1, the name can have any
+	// character but the delimiters of the tokens e.g Predicate:is-premium
+	_ReferenceRegex, _ = regexp.Compile("^[a-zA-Z_][a-zA-Z_0-9]*:[^ (),\"]+$")
+	_FunctionRegex, _ = regexp.Compile("^[a-zA-Z_][a-zA-Z_0-9]*$")
 	// TODO: this matches leading and trailing 0s need a fix for it
 	_DecimalRegex, _ = regexp.Compile("^-?[0-9][0-9]*(.[0-9]+)?$")
 }
@@ -49,10 +65,25 @@ func NewLexer() Lexer {
 }
 
 func NewLexerWithUDFs(ops ...UDF) Lexer {
+	return newLexer(ops, nil)
+}
+
+// NewLexerWithRegistry is a constructor to instantiate a Lexer that
+// recognizes the operators of the registry
+func NewLexerWithRegistry(registry *Registry) Lexer {
+	return newLexer(nil, registry)
+}
+
+func newLexer(ops []UDF, registry *Registry) Lexer {
 	localOperators := make(map[string]struct{})
 	for _, op := range ops {
 		localOperators[op.Token] = struct{}{}
 	}
+	if registry != nil {
+		for _, token := range registry.operatorTokens() {
+			localOperators[token] = struct{}{}
+		}
+	}
 
 	return &lexer{
 		localOperators: localOperators,
@@ -74,6 +105,16 @@ func (l *lexer) Lex(expression string) ([]*Token, error) {
 		if isDelimiter(val) {
 			continue
 		}
+		// an operator in place of an operand is a prefix unary operator e.g -a or !(a > b)
+		if _, ok := lexerState.nextValidStates[UnaryOperator]; ok && isUnaryOperator(val) {
+			tokens = append(tokens, &Token{
+				Type:  UnaryOperator,
+				Value: string(val),
+				Index: expressionStream.Position() - 1,
+			})
+			lexerState = stateTransitions[UnaryOperator]
+			continue
+		}
 
 		expressionStream.Rewind()
 		nextToken, err := l.getNextToken(expressionStream)
@@ -103,8 +144,30 @@ func (l *lexer) getNextToken(s *stream) (*Token, error) {
 		return scanString(s)
 	case '(', ')':
 		return scanParenthesis(s)
+	case ',':
+		s.GetNext()
+		return &Token{
+			Type:  Comma,
+			Value: ",",
+			Index: index,
+		}, nil
 	default:
 		token := getNonStringToken(s)
+		if l.isValidOperator(token) {
+			return &Token{
+				Type:  Operator,
+				Value: token,
+				Index: index,
+			}, nil
+		}
+		// a name followed by a parenthesis is a function call e.g max(a, b)
+		if s.Peek() == '(' && isValidFunctionName(token) {
+			return &Token{
+				Type:  Function,
+				Value: token,
+				Index: index,
+			}, nil
+		}
 		if isValidBool(token) {
 			b, _ := strconv.ParseBool(token)
 			return &Token{
@@ -113,9 +176,9 @@ func (l *lexer) getNextToken(s *stream) (*Token, error) {
 				Index: index,
 			}, nil
 		}
-		if l.isValidOperator(token) {
+		if isValidReference(token) {
 			return &Token{
-				Type:  Operator,
+				Type:  Reference,
 				Value: token,
 				Index: index,
 			}, nil
@@ -147,6 +210,14 @@ func isValidVariable(s string) bool {
 	return _VariableRegex.MatchString(s)
 }
 
+func isValidFunctionName(s string) bool {
+	return _FunctionRegex.MatchString(s)
+}
+
+func isValidReference(s string) bool {
+	return _ReferenceRegex.MatchString(s)
+}
+
 func isValidNumber(s string) bool {
 	return _DecimalRegex.MatchString(s)
 }
@@ -157,6 +228,11 @@ func (l *lexer) isValidOperator(s string) bool {
 	return ok || ok2
 }
 
+func isUnaryOperator(c rune) bool {
+	_, ok := _ValidUnaryOperators[c]
+	return ok
+}
+
 func isValidBool(s string) bool {
 	return s == "true" || s == "false"
 }
@@ -169,7 +245,7 @@ func getNonStringToken(s *stream) string {
 	token := make([]rune, 0)
 	for {
 		val := s.GetNext()
-		if isDelimiter(val) || val == _EndOfStream || val == ')' {
+		if isDelimiter(val) || val == _EndOfStream || val == ')' || val == '(' || val == ',' {
 			if val != _EndOfStream {
 				s.Rewind()
 			}
@@ -15,7 +15,10 @@ var stateTransitions = map[TokenType]*state{
 	None: &state{
 		currentState: None,
 		nextValidStates: map[TokenType]struct{}{
+			UnaryOperator:   {},
 			Variable:        {},
+			Function:        {},
+			Reference:       {},
 			String:          {},
 			Number:          {},
 			Bool:            {},
@@ -26,6 +29,16 @@ var stateTransitions = map[TokenType]*state{
 	Variable: &state{
 		currentState: Variable,
 		nextValidStates: map[TokenType]struct{}{
+			Comma:            {},
+			Operator:         {},
+			Eol:              {},
+			RightParenthesis: {},
+		},
+	},
+	Reference: &state{
+		currentState: Reference,
+		nextValidStates: map[TokenType]struct{}{
+			Comma:            {},
 			Operator:         {},
 			Eol:              {},
 			RightParenthesis: {},
@@ -34,6 +47,7 @@ var stateTransitions = map[TokenType]*state{
 	String: &state{
 		currentState: String,
 		nextValidStates: map[TokenType]struct{}{
+			Comma:            {},
 			Operator:         {},
 			Eol:              {},
 			RightParenthesis: {},
@@ -42,6 +56,7 @@ var stateTransitions = map[TokenType]*state{
 	Number: &state{
 		currentState: Number,
 		nextValidStates: map[TokenType]struct{}{
+			Comma:            {},
 			Operator:         {},
 			Eol:              {},
 			RightParenthesis: {},
@@ -50,6 +65,7 @@ var stateTransitions = map[TokenType]*state{
 	Bool: &state{
 		currentState: Bool,
 		nextValidStates: map[TokenType]struct{}{
+			Comma:            {},
 			Operator:         {},
 			Eol:              {},
 			RightParenthesis: {},
@@ -58,29 +74,69 @@ var stateTransitions = map[TokenType]*state{
 	Operator: &state{
 		currentState: Operator,
 		nextValidStates: map[TokenType]struct{}{
+			UnaryOperator:   {},
 			Variable:        {},
+			Function:        {},
+			Reference:       {},
 			String:          {},
 			Bool:            {},
 			Number:          {},
 			LeftParenthesis: {},
 		},
 	},
-	LeftParenthesis: &state{
-		currentState: LeftParenthesis,
+	UnaryOperator: &state{
+		currentState: UnaryOperator,
 		nextValidStates: map[TokenType]struct{}{
+			UnaryOperator:   {},
 			Variable:        {},
+			Function:        {},
+			Reference:       {},
 			String:          {},
 			Number:          {},
 			Bool:            {},
 			LeftParenthesis: {},
 		},
 	},
+	LeftParenthesis: &state{
+		currentState: LeftParenthesis,
+		nextValidStates: map[TokenType]struct{}{
+			UnaryOperator:    {},
+			Variable:         {},
+			Function:         {},
+			Reference:        {},
+			String:           {},
+			Number:           {},
+			Bool:             {},
+			LeftParenthesis:  {},
+			RightParenthesis: {},
+		},
+	},
 	RightParenthesis: &state{
 		currentState: RightParenthesis,
 		nextValidStates: map[TokenType]struct{}{
+			Comma:            {},
 			Operator:         {},
 			Eol:              {},
 			RightParenthesis: {},
 		},
 	},
+	Function: &state{
+		currentState: Function,
+		nextValidStates: map[TokenType]struct{}{
+			LeftParenthesis: {},
+		},
+	},
+	Comma: &state{
+		currentState: Comma,
+		nextValidStates: map[TokenType]struct{}{
+			UnaryOperator:   {},
+			Variable:        {},
+			Function:        {},
+			Reference:       {},
+			String:          {},
+			Number:          {},
+			Bool:            {},
+			LeftParenthesis: {},
+		},
+	},
 }
@@ -2,6 +2,7 @@ package expressions
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/anshal21/coffee-machine/lib"
 	"github.com/anshal21/coffee-machine/lib/errors"
@@ -63,6 +64,12 @@ func getOperator(operatorToken string) (OperatorFunc, error) {
 		return mul, nil
 	case "/":
 		return div, nil
+	case "//":
+		return sameOperand(intDiv, "//"), nil
+	case "%":
+		return sameOperand(mod, "%"), nil
+	case "^":
+		return sameOperand(pow, "^"), nil
 	case "<":
 		return lt, nil
 	case ">":
@@ -72,11 +79,15 @@ func getOperator(operatorToken string) (OperatorFunc, error) {
 	case ">=":
 		return gte, nil
 	case "==":
-		return equal, nil
+		return sameOperand(equal, "=="), nil
+	case "!=":
+		return sameOperand(notEqual, "!="), nil
 	case "||":
-		return or, nil
+		return sameOperand(or, "||"), nil
 	case "&&":
-		return and, nil
+		return sameOperand(and, "&&"), nil
+	case "in":
+		return in, nil
 	default:
 		return nil, errors.New(ErrUnsupportedOperation, fmt.Errorf("unsupported operator"))
 	}
@@ -130,7 +141,7 @@ func div(operand1 *evaluationResult, operand2 *evaluationResult, res *evaluation
 	switch operand1.Type {
 	case models.DataTypeNumber:
 		if *operand2.Value.Number == 0 {
-			return fmt.Errorf("encountered 0 value as denominatior")
+			return divisionByZeroError("/")
 		}
 		res.Value.Number = lib.Float64Ptr(*operand1.Value.Number / *operand2.Value.Number)
 		return nil
@@ -138,6 +149,46 @@ func div(operand1 *evaluationResult, operand2 *evaluationResult, res *evaluation
 	return incompatibleOperationError("/", operand1.Type)
 }
 
+// intDiv is the floor division of the numbers, the result is rounded
+// towards negative infinity e.g -7 // 2 is -4
+func intDiv(operand1 *evaluationResult, operand2 *evaluationResult, res *evaluationResult) error {
+	res.Type = operand1.Type
+	switch operand1.Type {
+	case models.DataTypeNumber:
+		if *operand2.Value.Number == 0 {
+			return divisionByZeroError("//")
+		}
+		res.Value.Number = lib.Float64Ptr(math.Floor(*operand1.Value.Number / *operand2.Value.Number))
+		return nil
+	}
+	return incompatibleOperationError("//", operand1.Type)
+}
+
+// mod is the remainder of the truncated division, it has the sign of
+// the dividend e.g -7 % 2 is -1
+func mod(operand1 *evaluationResult, operand2 *evaluationResult, res *evaluationResult) error {
+	res.Type = operand1.Type
+	switch operand1.Type {
+	case models.DataTypeNumber:
+		if *operand2.Value.Number == 0 {
+			return divisionByZeroError("%")
+		}
+		res.Value.Number = lib.Float64Ptr(math.Mod(*operand1.Value.Number, *operand2.Value.Number))
+		return nil
+	}
+	return incompatibleOperationError("%", operand1.Type)
+}
+
+func pow(operand1 *evaluationResult, operand2 *evaluationResult, res *evaluationResult) error {
+	res.Type = operand1.Type
+	switch operand1.Type {
+	case models.DataTypeNumber:
+		res.Value.Number = lib.Float64Ptr(math.Pow(*operand1.Value.Number, *operand2.Value.Number))
+		return nil
+	}
+	return incompatibleOperationError("^", operand1.Type)
+}
+
 func lt(operand1 *evaluationResult, operand2 *evaluationResult, res *evaluationResult) error {
 	res.Type = models.DataTypeBool
 	switch operand1.Type {
@@ -203,6 +254,17 @@ func equal(operand1 *evaluationResult, operand2 *evaluationResult, res *evaluati
 	return incompatibleOperationError("==", operand1.Type)
 }
 
+func notEqual(operand1 *evaluationResult, operand2 *evaluationResult, res *evaluationResult) error {
+	if err := equal(operand1, operand2, res); err != nil {
+		if err.(*errors.Error).Code == ErrIncompatibleOperation {
+			err = incompatibleOperationError("!=", operand1.Type)
+		}
+		return err
+	}
+	res.Value.Bool = lib.BoolPtr(!*res.Value.Bool)
+	return nil
+}
+
 func or(operand1 *evaluationResult, operand2 *evaluationResult, res *evaluationResult) error {
 	res.Type = models.DataTypeBool
 	switch operand1.Type {
@@ -223,6 +285,29 @@ func and(operand1 *evaluationResult, operand2 *evaluationResult, res *evaluation
 	return incompatibleOperationError("&&", operand1.Type)
 }
 
+func in(operand1 *evaluationResult, operand2 *evaluationResult, res *evaluationResult) error {
+	res.Type = models.DataTypeBool
+	if operand2.Type != models.DataTypeList {
+		return incompatibleOperationError("in", operand2.Type)
+	}
+	if operand1.Type == models.DataTypeList {
+		return incompatibleOperationError("in", operand1.Type)
+	}
+	value := operand1.Value.Interface()
+	for _, element := range operand2.Value.List {
+		if element.Interface() == value {
+			res.Value.Bool = lib.BoolPtr(true)
+			return nil
+		}
+	}
+	res.Value.Bool = lib.BoolPtr(false)
+	return nil
+}
+
 // func incompatibleOperationError(op string, operandType models.DataType) *errors.Error {
 // 	return errors.New(ErrIncompatibleOperation, fmt.Errorf("operation '%v' is not compatible with '%v' type", op, operandType))
 // }
+
+func divisionByZeroError(op string) *errors.Error {
+	return errors.New(ErrDivisionByZero, fmt.Errorf("division by zero in '%v' operation", op))
+}
@@ -0,0 +1,55 @@
+package expressions
+
+import (
+	"github.com/anshal21/coffee-machine/lib/models"
+)
+
+// Option represent an option type to override the default behaviour
+// of an expression
+type Option func(c *config)
+
+type config struct {
+	udfs          []UDF
+	resolver      ReferenceResolver
+	variableTypes map[string]models.DataType
+	registry      *Registry
+}
+
+// ReferenceResolver resolves a reference used in an expression e.g
+// Predicate:P1 to the expression it refers to
+// The referenced expression is evaluated in place of the reference
+type ReferenceResolver func(reference string) (Expression, error)
+
+// WithUDFs adds user defined operators to the expression
+func WithUDFs(ops ...UDF) Option {
+	return func(c *config) {
+		c.udfs = append(c.udfs, ops...)
+	}
+}
+
+// WithReferenceResolver sets the resolver for the references used in
+// the expression, an expression with references can't be created without it
+func WithReferenceResolver(resolver ReferenceResolver) Option {
+	return func(c *config) {
+		c.resolver = resolver
+	}
+}
+
+// WithVariableTypes sets the types of the variables used in the expression
+// The types are used to reject ill-typed expressions at the time of creation
+// e.g a + 1 where a is a string, the variables without a type are checked
+// only on evaluation
+func WithVariableTypes(types map[string]models.DataType) Option {
+	return func(c *config) {
+		c.variableTypes = types
+	}
+}
+
+// WithRegistry sets the registry of the functions and the operators defined
+// by the callers, they can be used in the expression along with the builtin
+// ones
+func WithRegistry(registry *Registry) Option {
+	return func(c *config) {
+		c.registry = registry
+	}
+}
@@ -18,12 +18,13 @@ func NewParser() Parser {
 }
 
 type parser struct {
+	registry *Registry
 }
 
 func (p *parser) Parse(tokens []*Token) (*syntaxTree, error) {
 	root := &node{}
 	pos := 0
-	err := parserHelper(tokens, &pos, root)
+	err := parserHelper(tokens, &pos, root, p.registry)
 	if err != nil {
 		return nil, err
 	}
@@ -32,11 +33,24 @@ func (p *parser) Parse(tokens []*Token) (*syntaxTree, error) {
 	}, nil
 }
 
-func parserHelper(tokens []*Token, pos *int, root *node) error {
+func parserHelper(tokens []*Token, pos *int, root *node, registry *Registry) error {
 	operandStack := newStack(len(tokens))
 	operatorStack := newStack(len(tokens))
 
 	buildExpr := func(op *Token) error {
+		if op.Type == UnaryOperator {
+			operand := toNode(operandStack.Top())
+			operandStack.Pop()
+			if operand == nil {
+				return errors.New(ErrInvalidExpression,
+					fmt.Errorf("missing operand for operator %v at position %v", op.Value, op.Index))
+			}
+			operandStack.Push(&node{
+				Token:     op,
+				LeftChild: operand,
+			})
+			return nil
+		}
 		operand1 := toNode(operandStack.Top())
 		operandStack.Pop()
 		operand2 := toNode(operandStack.Top())
@@ -53,19 +67,69 @@ func parserHelper(tokens []*Token, pos *int, root *node) error {
 		return nil
 	}
 
+	buildCall := func(function *Token, argCount int) error {
+		args := make([]*node, argCount)
+		for index := argCount - 1; index >= 0; index-- {
+			arg := toNode(operandStack.Top())
+			operandStack.Pop()
+			if arg == nil {
+				return errors.New(ErrInvalidExpression,
+					fmt.Errorf("missing arguments for function %v at position %v", function.Value, function.Index))
+			}
+			args[index] = arg
+		}
+		operandStack.Push(&node{
+			Token: function,
+			Args:  args,
+		})
+		return nil
+	}
+
+	// argCounts holds the number of arguments seen so far in each of the
+	// open parenthesis, a parenthesis with more than one argument should
+	// belong to a function call
+	// argStarts holds the size of the operand stack at each of the open
+	// parenthesis, to tell an empty parenthesis e.g now()
+	argCounts := make([]int, 0)
+	argStarts := make([]int, 0)
+
 OuterLoop:
 	for _, val := range tokens {
 		switch val.Type {
 		case LeftParenthesis:
 			operatorStack.Push(val)
-		case Variable, String, Number, Bool:
+			argCounts = append(argCounts, 1)
+			argStarts = append(argStarts, operandStack.Len())
+		case Function:
+			operatorStack.Push(val)
+		case Comma:
+			for {
+				topEle := toToken(operatorStack.Top())
+				if topEle == nil {
+					return errors.New(ErrInvalidExpression, fmt.Errorf("unexpected ',' at position %v", val.Index))
+				}
+				if topEle.Type == LeftParenthesis {
+					argCounts[len(argCounts)-1]++
+					continue OuterLoop
+				}
+				operatorStack.Pop()
+				err := buildExpr(topEle)
+				if err != nil {
+					return err
+				}
+			}
+		case Variable, String, Number, Bool, Reference, List:
 			operandStack.Push(&node{
 				Token: val,
 			})
+		case UnaryOperator:
+			// a prefix operator doesn't have a left operand to build yet
+			operatorStack.Push(val)
 		case Operator:
 			for {
 				topEle := toToken(operatorStack.Top())
-				if topEle == nil || topEle.Type == LeftParenthesis || operatorPrecedence(topEle.Value.(string)) < operatorPrecedence(val.Value.(string)) {
+				if topEle == nil || topEle.Type == LeftParenthesis || precedence(topEle, registry) < precedence(val, registry) ||
+					(precedence(topEle, registry) == precedence(val, registry) && isRightAssociative(val, registry)) {
 					operatorStack.Push(val)
 					continue OuterLoop
 				}
@@ -83,6 +147,27 @@ OuterLoop:
 					return errors.New(ErrInvalidExpression, fmt.Errorf("no matching '(' for ')' at position %v", val.Index))
 				}
 				if topEle.Type == LeftParenthesis {
+					argCount := argCounts[len(argCounts)-1]
+					argCounts = argCounts[:len(argCounts)-1]
+					empty := operandStack.Len() == argStarts[len(argStarts)-1]
+					argStarts = argStarts[:len(argStarts)-1]
+					if function := toToken(operatorStack.Top()); function != nil && function.Type == Function {
+						operatorStack.Pop()
+						if empty {
+							argCount = 0
+						}
+						if err := buildCall(function, argCount); err != nil {
+							return err
+						}
+						continue OuterLoop
+					}
+					if empty {
+						return errors.New(ErrInvalidExpression, fmt.Errorf("empty parenthesis at position %v", topEle.Index))
+					}
+					if argCount > 1 {
+						return errors.New(ErrInvalidExpression,
+							fmt.Errorf("unexpected ',' in the parenthesis at position %v", topEle.Index))
+					}
 					continue OuterLoop
 				}
 				err := buildExpr(topEle)
@@ -106,25 +191,66 @@ OuterLoop:
 		operatorStack.Pop()
 	}
 
-	*root = *(toNode(operandStack.Top()))
+	top := toNode(operandStack.Top())
+	if top == nil {
+		return errors.New(ErrInvalidExpression, fmt.Errorf("empty expression"))
+	}
+	*root = *top
 	return nil
 }
 
+// precedence returns the precedence of an operator token, the unary
+// operators bind tighter than all the binary operators but ^
+// i.e -a ^ 2 is -(a ^ 2) and -a * b is (-a) * b
+// The operators of the registry have the precedence they are registered with
+func precedence(op *Token, registry *Registry) int {
+	if op.Type == UnaryOperator {
+		return _unaryPrecedence
+	}
+	if custom, ok := registry.operator(op.Value.(string)); ok {
+		return custom.precedence
+	}
+	return operatorPrecedence(op.Value.(string))
+}
+
+const (
+	_unaryPrecedence = 5
+)
+
+// operatorPrecedence returns the precedence of a binary operator, && binds
+// tighter than || and both bind looser than the comparisons, the operators
+// added with WithUDFs bind the loosest
 func operatorPrecedence(op string) int {
 	switch op {
 	case "^":
+		return 6
+	case "*", "/", "//", "%":
 		return 4
-	case "*", "/":
-		return 3
 	case "+", "-":
+		return 3
+	case ">", "<", "==", "!=", ">=", "<=", "in":
 		return 2
-	case ">", "<", "==", ">=", "<=":
+	case "&&":
 		return 1
+	case "||":
+		return 0
 	default:
 		return -1
 	}
 }
 
+// isRightAssociative tells if the operator groups from the right
+// i.e a ^ b ^ c is a ^ (b ^ c)
+func isRightAssociative(op *Token, registry *Registry) bool {
+	if op.Type != Operator {
+		return false
+	}
+	if custom, ok := registry.operator(op.Value.(string)); ok {
+		return custom.rightAssociative
+	}
+	return op.Value.(string) == "^"
+}
+
 func toToken(val interface{}) *Token {
 	if val != nil {
 		return val.(*Token)
@@ -0,0 +1,205 @@
+package expressions
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/anshal21/coffee-machine/lib/errors"
+	"github.com/anshal21/coffee-machine/lib/models"
+)
+
+// Registry holds the functions and the infix operators defined by the
+// callers, it is passed to the expressions with WithRegistry
+// example usage:
+// registry := NewRegistry()
+// err := registry.RegisterFunction(FunctionDefinition{Name: "discount", ...})
+// expr, err := New("discount(price) > 10", WithRegistry(registry))
+// A Registry is safe for concurrent use, an expression sees the functions
+// and the operators registered before it was created
+type Registry struct {
+	mu        sync.RWMutex
+	functions map[string]*function
+	operators map[string]*customOperator
+}
+
+// FunctionDefinition is the definition of a function that can be called in an
+// expression e.g discount(price)
+// The arguments are checked against the Params, the last param can be
+// repeated if the function is Variadic and a param of DataTypeUnknown
+// accepts a value of any type
+type FunctionDefinition struct {
+	Name     string
+	Params   []models.DataType
+	Variadic bool
+	Result   models.DataType
+	Call     func(args []models.Value) (models.Value, error)
+}
+
+// Associativity is the side an infix operator groups from when it's
+// chained with an operator of the same precedence
+type Associativity int
+
+const (
+	// LeftAssociative operators group from the left i.e a op b op c is (a op b) op c
+	LeftAssociative Associativity = iota
+	// RightAssociative operators group from the right i.e a op b op c is a op (b op c)
+	RightAssociative
+)
+
+// OperatorDefinition is the definition of an infix operator e.g a contains b
+// The precedence is relative to the builtin operators, ^ has 6, the prefix
+// operators 5, * / // and % 4, + and - 3, the comparisons 2, && 1 and || 0
+// The operands are checked against Left and Right, DataTypeUnknown accepts
+// a value of any type
+type OperatorDefinition struct {
+	Token         string
+	Precedence    int
+	Associativity Associativity
+	Left          models.DataType
+	Right         models.DataType
+	Result        models.DataType
+	Apply         func(left, right models.Value) (models.Value, error)
+}
+
+// customOperator is a registered operator, it is evaluated as a call of
+// its function with the operands as the arguments
+type customOperator struct {
+	precedence       int
+	rightAssociative bool
+	function         *function
+}
+
+// NewRegistry is a constructor to instantiate an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{
+		functions: make(map[string]*function),
+		operators: make(map[string]*customOperator),
+	}
+}
+
+// RegisterFunction adds a function to the registry, the name of the
+// function should neither be taken by a builtin function or operator nor by
+// another registered function or operator
+func (r *Registry) RegisterFunction(fn FunctionDefinition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case !isValidFunctionName(fn.Name) || isValidBool(fn.Name):
+		return invalidRegistrationError("invalid function name '%v'", fn.Name)
+	case isBuiltinOperator(fn.Name) || r.isTaken(fn.Name):
+		return invalidRegistrationError("name '%v' has been used already", fn.Name)
+	case fn.Call == nil:
+		return invalidRegistrationError("function %v has no implementation", fn.Name)
+	case fn.Variadic && len(fn.Params) == 0:
+		return invalidRegistrationError("variadic function %v has no params", fn.Name)
+	case !isConcrete(fn.Result):
+		return invalidRegistrationError("function %v has invalid result type '%v'", fn.Name, fn.Result)
+	}
+
+	r.functions[fn.Name] = &function{
+		name:     fn.Name,
+		params:   append([]models.DataType(nil), fn.Params...),
+		variadic: fn.Variadic,
+		result:   fn.Result,
+		call:     fn.Call,
+	}
+	return nil
+}
+
+// RegisterOperator adds an infix operator to the registry, the token should
+// neither be taken by a builtin operator nor by another registered function
+// or operator, and it can't contain spaces, parenthesis, commas or quotes
+func (r *Registry) RegisterOperator(op OperatorDefinition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case op.Token == "" || strings.ContainsAny(op.Token, " (),\"") || isValidBool(op.Token):
+		return invalidRegistrationError("invalid operator '%v'", op.Token)
+	case isBuiltinOperator(op.Token) || r.isTaken(op.Token):
+		return invalidRegistrationError("operator '%v' has been used already", op.Token)
+	case op.Apply == nil:
+		return invalidRegistrationError("operator '%v' has no implementation", op.Token)
+	case op.Associativity != LeftAssociative && op.Associativity != RightAssociative:
+		return invalidRegistrationError("operator '%v' has invalid associativity %v", op.Token, op.Associativity)
+	case !isConcrete(op.Result):
+		return invalidRegistrationError("operator '%v' has invalid result type '%v'", op.Token, op.Result)
+	}
+
+	apply := op.Apply
+	r.operators[op.Token] = &customOperator{
+		precedence:       op.Precedence,
+		rightAssociative: op.Associativity == RightAssociative,
+		function: &function{
+			name:   op.Token,
+			params: []models.DataType{op.Left, op.Right},
+			result: op.Result,
+			call: func(args []models.Value) (models.Value, error) {
+				return apply(args[0], args[1])
+			},
+		},
+	}
+	return nil
+}
+
+func (r *Registry) isTaken(name string) bool {
+	_, builtin := _builtinFunctions[name]
+	_, function := r.functions[name]
+	_, operator := r.operators[name]
+	return builtin || function || operator
+}
+
+// function returns the function called by the name, the builtin
+// functions are looked up first
+func (r *Registry) function(name string) (*function, bool) {
+	if fn, ok := _builtinFunctions[name]; ok {
+		return fn, true
+	}
+	if r == nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn, ok := r.functions[name]
+	return fn, ok
+}
+
+func (r *Registry) operator(token string) (*customOperator, bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	op, ok := r.operators[token]
+	return op, ok
+}
+
+// operatorTokens returns the tokens of the registered operators
+func (r *Registry) operatorTokens() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tokens := make([]string, 0, len(r.operators))
+	for token := range r.operators {
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func isBuiltinOperator(token string) bool {
+	_, ok := _ValidGlobalOperators[token]
+	return ok
+}
+
+func isConcrete(dataType models.DataType) bool {
+	switch dataType {
+	case models.DataTypeNumber, models.DataTypeString, models.DataTypeBool, models.DataTypeList:
+		return true
+	}
+	return false
+}
+
+func invalidRegistrationError(format string, args ...interface{}) *errors.Error {
+	return errors.New(ErrInvalidRegistration, fmt.Errorf(format, args...))
+}
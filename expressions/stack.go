@@ -30,6 +30,11 @@ func (s *stack) Pop() {
 	}
 }
 
+// Len returns the number of elements in the stack
+func (s *stack) Len() int {
+	return s.index + 1
+}
+
 // Push adds the element to the stack top
 func (s *stack) Push(val interface{}) {
 	s.index++
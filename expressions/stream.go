@@ -17,6 +17,14 @@ func (s *stream) GetNext() rune {
 	return s.s[s.pos-1]
 }
 
+// Peek returns the next rune without moving ahead in the stream
+func (s *stream) Peek() rune {
+	if s.pos >= len(s.s) {
+		return _EndOfStream
+	}
+	return s.s[s.pos]
+}
+
 func (s *stream) Position() int {
 	return s.pos
 }
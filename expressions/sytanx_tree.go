@@ -9,11 +9,19 @@ import (
 )
 
 // Node represents a node of a syntax tree
-// Node can either be an Operand or Operator node
+// Node can either be an Operand or Operator node, a unary
+// Operator node has only the LeftChild
+// A Function node holds the arguments of the call in Args
+// and the function it calls, resolved by its name
+// Target of a Reference node is the root of the
+// referenced syntax tree
 type node struct {
 	Token      *Token
 	LeftChild  *node
 	RightChild *node
+	Target     *node
+	Args       []*node
+	Function   *function
 }
 
 // SyntaxTree represents the AST composed of nodes
@@ -92,11 +100,19 @@ func (e *evaluator) boolEvaluationResult(val bool) *evaluationResult {
 	return res
 }
 
+func (e *evaluator) listEvaluationResult(val []models.Value) *evaluationResult {
+	res := e.resultPool.Get().(*evaluationResult)
+	res.Type = models.DataTypeList
+	res.Value.List = val
+	return res
+}
+
 func (e *evaluator) returnResultToPool(results ...*evaluationResult) {
 	for _, res := range results {
 		res.Value.String = nil
 		res.Value.Number = nil
 		res.Value.Bool = nil
+		res.Value.List = nil
 		e.resultPool.Put(res)
 	}
 }
@@ -111,11 +127,32 @@ func (e *evaluator) evaluteHelper(curr *node, values map[string]interface{}) (*e
 		return e.numberEvaluationResult(curr.Token.Value.(float64)), nil
 	case Bool:
 		return e.boolEvaluationResult(curr.Token.Value.(bool)), nil
+	case List:
+		return e.listEvaluationResult(curr.Token.Value.([]models.Value)), nil
+	case Reference:
+		if curr.Target == nil {
+			return nil, errors.New(ErrInvalidReference, fmt.Errorf("unresolved reference %v at position %v", curr.Token.Value, curr.Token.Index))
+		}
+		return e.evaluteHelper(curr.Target, values)
+	case Function:
+		return e.callFunction(curr, values)
+	case UnaryOperator:
+		res, err := e.evaluteHelper(curr.LeftChild, values)
+		if err != nil {
+			return nil, err
+		}
+		return e.applyUnaryOperator(res, curr.Token)
 	case Operator:
+		if curr.Function != nil {
+			return e.callFunction(curr, values)
+		}
 		res1, err := e.evaluteHelper(curr.LeftChild, values)
 		if err != nil {
 			return nil, err
 		}
+		if shortCircuits(curr.Token, res1) {
+			return res1, nil
+		}
 
 		res2, err := e.evaluteHelper(curr.RightChild, values)
 		if err != nil {
@@ -132,6 +169,22 @@ func (e *evaluator) evaluteHelper(curr *node, values map[string]interface{}) (*e
 	return nil, fmt.Errorf("unsupported token type %v", curr.Token.Type)
 }
 
+// shortCircuits tells if the result of a logical operation is decided by its
+// left operand alone i.e false && x or true || x, the right operand is not
+// evaluated in that case
+func shortCircuits(operator *Token, left *evaluationResult) bool {
+	if left.Type != models.DataTypeBool {
+		return false
+	}
+	switch operator.Value.(string) {
+	case "&&":
+		return !*left.Value.Bool
+	case "||":
+		return *left.Value.Bool
+	}
+	return false
+}
+
 func (e *evaluator) resolveVariableValue(token *Token, values map[string]interface{}) (*evaluationResult, error) {
 	val, ok := values[token.Value.(string)]
 	if !ok {
@@ -147,6 +200,12 @@ func (e *evaluator) resolveVariableValue(token *Token, values map[string]interfa
 		return e.numberEvaluationResult(float64(val.(int))), nil
 	case bool:
 		return e.boolEvaluationResult(val.(bool)), nil
+	case []interface{}, []string, []float64, []int:
+		list, err := listValue(val)
+		if err != nil {
+			return nil, err
+		}
+		return e.listEvaluationResult(list), nil
 	}
 	return nil, fmt.Errorf("invalid variable type %v", val)
 }
@@ -168,6 +227,12 @@ func inorderTraversal(node *node, prefix string, level int) {
 	if node.Token.Type == Operator {
 		inorderTraversal(node.LeftChild, nextPrefix, level+1)
 	}
+	if node.Token.Type == UnaryOperator {
+		defer inorderTraversal(node.LeftChild, nextPrefix, level+1)
+	}
+	for _, arg := range node.Args {
+		defer inorderTraversal(arg, nextPrefix, level+1)
+	}
 	fmt.Printf("|\n|%v> %v [%v]\n", prefix, node.Token.Value, node.Token.Type)
 	if node.Token.Type == Operator {
 		inorderTraversal(node.RightChild, nextPrefix, level+1)
@@ -190,8 +255,8 @@ func (e *evaluator) applyOperator(res1 *evaluationResult, res2 *evaluationResult
 
 	if err != nil {
 		e.returnResultToPool(response)
-		if e := err.(*errors.Error); e.Code == ErrIncompatibleOperation {
-			err = errors.New(ErrIncompatibleOperation, fmt.Errorf("%v at position %v", e.Msg, operation.Index))
+		if e, ok := err.(*errors.Error); ok && (e.Code == ErrIncompatibleOperation || e.Code == ErrDivisionByZero) {
+			err = errors.New(e.Code, fmt.Errorf("%v at position %v", e.Msg, operation.Index))
 		}
 		return nil, err
 	}
@@ -199,6 +264,62 @@ func (e *evaluator) applyOperator(res1 *evaluationResult, res2 *evaluationResult
 	return response, nil
 }
 
+// callFunction evaluates the arguments of a function call, or the operands
+// of a registered operator, and calls the function, the types of the
+// arguments that were not known while parsing are checked before the call
+func (e *evaluator) callFunction(curr *node, values map[string]interface{}) (*evaluationResult, error) {
+	nodes := operands(curr)
+	args := make([]models.Value, 0, len(nodes))
+	types := make([]models.DataType, 0, len(nodes))
+	for _, operand := range nodes {
+		res, err := e.evaluteHelper(operand, values)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, *res.Value)
+		types = append(types, res.Type)
+		e.returnResultToPool(res)
+	}
+	if err := checkArgumentTypes(curr, types); err != nil {
+		return nil, err
+	}
+
+	value, err := curr.Function.call(args)
+	if err != nil {
+		if e, ok := err.(*errors.Error); ok {
+			return nil, errors.New(e.Code, fmt.Errorf("%v at position %v", e.Msg, curr.Token.Index))
+		}
+		return nil, err
+	}
+	if !holds(value, curr.Function.result) {
+		return nil, errors.New(ErrIncompatibleOperation, fmt.Errorf("function %v returned a value that isn't of type %v at position %v",
+			curr.Function.name, curr.Function.result, curr.Token.Index))
+	}
+	res := e.resultPool.Get().(*evaluationResult)
+	res.Type = curr.Function.result
+	*res.Value = value
+	return res, nil
+}
+
+func (e *evaluator) applyUnaryOperator(operand *evaluationResult, operation *Token) (*evaluationResult, error) {
+	op := operation.Value.(string)
+	var res *evaluationResult
+	switch {
+	case op == "!" && operand.Type == models.DataTypeBool:
+		res = e.boolEvaluationResult(!*operand.Value.Bool)
+	case op == "-" && operand.Type == models.DataTypeNumber:
+		res = e.numberEvaluationResult(-*operand.Value.Number)
+	case op == "+" && operand.Type == models.DataTypeNumber:
+		res = e.numberEvaluationResult(*operand.Value.Number)
+	default:
+		err := incompatibleOperationError(op, operand.Type)
+		e.returnResultToPool(operand)
+		return nil, errors.New(ErrIncompatibleOperation, fmt.Errorf("%v at position %v", err.Msg, operation.Index))
+	}
+	e.returnResultToPool(operand)
+	return res, nil
+}
+
 func incompatibleOperationError(op string, operandType models.DataType) *errors.Error {
 	return errors.New(ErrIncompatibleOperation, fmt.Errorf("operation '%v' is not compatible with '%v' type", op, operandType))
 }
@@ -1,10 +1,15 @@
 package tests
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
 	"testing"
 
 	"github.com/anshal21/coffee-machine/expressions"
 	"github.com/anshal21/coffee-machine/lib"
+	liberrors "github.com/anshal21/coffee-machine/lib/errors"
 	"github.com/anshal21/coffee-machine/lib/models"
 	"github.com/stretchr/testify/assert"
 )
@@ -16,6 +21,7 @@ func Test_Expressions(t *testing.T) {
 		variables   map[string]interface{}
 		outputValue interface{}
 		udfs        []expressions.UDF
+		options     []expressions.Option
 		outputType  models.DataType
 		err         error
 	}{
@@ -62,12 +68,278 @@ func Test_Expressions(t *testing.T) {
 			outputValue: float64(140),
 			outputType:  models.DataTypeNumber,
 		},
+		{
+			name:       "reference | resolved to an expression",
+			expression: "(Expr:TOTAL) * c",
+			variables: map[string]interface{}{
+				"a": 10,
+				"b": 20,
+				"c": 2,
+			},
+			options: []expressions.Option{
+				expressions.WithReferenceResolver(func(reference string) (expressions.Expression, error) {
+					return expressions.New("a + b")
+				}),
+			},
+			outputValue: float64(60),
+			outputType:  models.DataTypeNumber,
+		},
+		{
+			name:       "list | element in list variable",
+			expression: "country in countries",
+			variables: map[string]interface{}{
+				"country":   "IN",
+				"countries": []string{"IN", "US"},
+			},
+			outputValue: true,
+			outputType:  models.DataTypeBool,
+		},
+		{
+			name:       "list | element not in constant list",
+			expression: "a in Const:LIST",
+			variables: map[string]interface{}{
+				"a": 3,
+			},
+			options: []expressions.Option{
+				expressions.WithReferenceResolver(func(reference string) (expressions.Expression, error) {
+					return expressions.NewConstant([]interface{}{1, 2.5, "3"})
+				}),
+			},
+			outputValue: false,
+			outputType:  models.DataTypeBool,
+		},
+		{
+			name:       "types | ill-typed literals",
+			expression: "\"abc\" > 3",
+			err:        errors.New("cannot apply '>' operation on type 'string' and 'number' at position 6"),
+		},
+		{
+			name:       "types | ill-typed variable",
+			expression: "a && b > 2",
+			options: []expressions.Option{
+				expressions.WithVariableTypes(map[string]models.DataType{
+					"a": models.DataTypeNumber,
+				}),
+			},
+			err: errors.New("cannot apply '&&' operation on type 'number' and 'bool' at position 2"),
+		},
+		{
+			name:       "unary | negation",
+			expression: "-a + b * -2",
+			variables: map[string]interface{}{
+				"a": 10,
+				"b": 3,
+			},
+			outputValue: float64(-16),
+			outputType:  models.DataTypeNumber,
+		},
+		{
+			name:       "unary | negated parenthesis",
+			expression: "-(a - b) * +2",
+			variables: map[string]interface{}{
+				"a": 10,
+				"b": 3,
+			},
+			outputValue: float64(-14),
+			outputType:  models.DataTypeNumber,
+		},
+		{
+			name:       "unary | logical not",
+			expression: "!is_blocked && !(a > b)",
+			variables: map[string]interface{}{
+				"is_blocked": false,
+				"a":          1,
+				"b":          3,
+			},
+			outputValue: true,
+			outputType:  models.DataTypeBool,
+		},
+		{
+			name:       "unary | double negation",
+			expression: "!!is_blocked",
+			variables: map[string]interface{}{
+				"is_blocked": true,
+			},
+			outputValue: true,
+			outputType:  models.DataTypeBool,
+		},
+		{
+			name:       "unary | missing operand",
+			expression: "a * -",
+			err:        errors.New("missing operands for operator * at position 2"),
+		},
+		{
+			name:       "unary | ill-typed operand",
+			expression: "!\"abc\"",
+			err:        errors.New("cannot apply '!' operation on type 'string' at position 0"),
+		},
+		{
+			name:       "logical | && binds tighter than ||",
+			expression: "a > 1 || b > 2 && c > 3",
+			variables: map[string]interface{}{
+				"a": 2,
+				"b": 0,
+				"c": 0,
+			},
+			outputValue: true,
+			outputType:  models.DataTypeBool,
+		},
+		{
+			name:       "logical | comparisons bind tighter than &&",
+			expression: "a == 2 && b + 1 == 1 || c > 3",
+			variables: map[string]interface{}{
+				"a": 2,
+				"b": 0,
+				"c": 0,
+			},
+			outputValue: true,
+			outputType:  models.DataTypeBool,
+		},
+		{
+			name:       "logical | || short-circuits",
+			expression: "a > 1 || missing > 2",
+			variables: map[string]interface{}{
+				"a": 2,
+			},
+			outputValue: true,
+			outputType:  models.DataTypeBool,
+		},
+		{
+			name:       "logical | && short-circuits",
+			expression: "a > 1 && b / 0 > 2",
+			variables: map[string]interface{}{
+				"a": 0,
+				"b": 1,
+			},
+			outputValue: false,
+			outputType:  models.DataTypeBool,
+		},
+		{
+			name:       "operators | not equal",
+			expression: "a != b && c != \"x\"",
+			variables: map[string]interface{}{
+				"a": 1,
+				"b": 2,
+				"c": "y",
+			},
+			outputValue: true,
+			outputType:  models.DataTypeBool,
+		},
+		{
+			name:       "operators | modulo and integer division",
+			expression: "a % 4 + a // 4 * 10",
+			variables: map[string]interface{}{
+				"a": 23,
+			},
+			outputValue: float64(53),
+			outputType:  models.DataTypeNumber,
+		},
+		{
+			name:        "operators | integer division rounds down",
+			expression:  "-7 // 2",
+			outputValue: float64(-4),
+			outputType:  models.DataTypeNumber,
+		},
+		{
+			name:        "operators | power is right associative",
+			expression:  "2 ^ 3 ^ 2",
+			outputValue: float64(512),
+			outputType:  models.DataTypeNumber,
+		},
+		{
+			name:       "operators | power binds tighter than unary minus",
+			expression: "-a ^ 2",
+			variables: map[string]interface{}{
+				"a": 3,
+			},
+			outputValue: float64(-9),
+			outputType:  models.DataTypeNumber,
+		},
+		{
+			name:       "reference | without a resolver",
+			expression: "Expr:TOTAL * c",
+			err:        errors.New("no resolver for reference Expr:TOTAL at position 0"),
+		},
+		{
+			name:       "functions | max and round",
+			expression: "max(a, b) + round(price, 2)",
+			variables: map[string]interface{}{
+				"a":     3,
+				"b":     7,
+				"price": 1.005001,
+			},
+			outputValue: float64(8.01),
+			outputType:  models.DataTypeNumber,
+		},
+		{
+			name:       "functions | nested calls",
+			expression: "min(max(a, 1), 10) == 10",
+			variables: map[string]interface{}{
+				"a": 25,
+			},
+			outputValue: true,
+			outputType:  models.DataTypeBool,
+		},
+		{
+			name:       "functions | variadic",
+			expression: "max(a, b, c, 4)",
+			variables: map[string]interface{}{
+				"a": 3,
+				"b": 9,
+				"c": -1,
+			},
+			outputValue: float64(9),
+			outputType:  models.DataTypeNumber,
+		},
+		{
+			name:       "functions | unary operator on a call",
+			expression: "-abs(x) + floor(2.7) * ceil(1.2)",
+			variables: map[string]interface{}{
+				"x": -3,
+			},
+			outputValue: float64(1),
+			outputType:  models.DataTypeNumber,
+		},
+		{
+			name:       "functions | call of an expression",
+			expression: "sqrt(pow(a, 2) + 16) > 4",
+			variables: map[string]interface{}{
+				"a": 3,
+			},
+			outputValue: true,
+			outputType:  models.DataTypeBool,
+		},
+		{
+			name:       "functions | too few arguments",
+			expression: "max(a) > 1",
+			err:        errors.New("function max expects at least 2 arguments, found 1 at position 0"),
+		},
+		{
+			name:       "functions | too many arguments",
+			expression: "round(a, 2, 3)",
+			err:        errors.New("function round expects 1 to 2 arguments, found 3 at position 0"),
+		},
+		{
+			name:       "functions | unknown function",
+			expression: "foo(a) > 1",
+			err:        errors.New("unknown function foo at position 0"),
+		},
+		{
+			name:       "functions | argument of a wrong type",
+			expression: "sqrt(\"x\")",
+			err:        errors.New("function sqrt expects number for argument 1, found string at position 0"),
+		},
+		{
+			name:       "functions | comma outside of a call",
+			expression: "(a, b)",
+			err:        errors.New("unexpected ',' in the parenthesis at position 0"),
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			expectedRes := getExpectedResponse(test.outputType, test.outputValue)
-			evalautor, err := expressions.New(test.expression)
+			evalautor, err := expressions.New(test.expression, test.options...)
 
 			if test.udfs != nil {
 				evalautor, err = expressions.NewExpressionsWithUDFs(test.expression, test.udfs...)
@@ -101,7 +373,349 @@ func getExpectedResponse(dataType models.DataType, value interface{}) *expressio
 				Number: lib.Float64Ptr(value.(float64)),
 			},
 		}
+	case models.DataTypeBool:
+		return &expressions.EvaluationResponse{
+			Type: dataType,
+			Value: models.Value{
+				Bool: lib.BoolPtr(value.(bool)),
+			},
+		}
 	default:
 		return nil
 	}
 }
+
+func Test_DivisionByZero(t *testing.T) {
+	for _, expression := range []string{"a / b", "a // b", "a % b"} {
+		t.Run(expression, func(t *testing.T) {
+			evaluable, err := expressions.New(expression)
+			assert.NoError(t, err)
+			_, err = evaluable.Evaluate(&expressions.EvaluationRequest{
+				Variables: map[string]interface{}{
+					"a": 1,
+					"b": 0,
+				},
+			})
+			assert.Error(t, err)
+			evaluationErr, ok := err.(*liberrors.Error)
+			assert.True(t, ok)
+			assert.Equal(t, expressions.ErrDivisionByZero, evaluationErr.Code)
+			assert.Equal(t, fmt.Sprintf("division by zero in '%v' operation at position 2", expression[2:len(expression)-2]), evaluationErr.Msg)
+		})
+	}
+}
+
+func Test_IncompatibleOperands(t *testing.T) {
+	for _, expression := range []string{"a // s", "a % s", "a ^ s"} {
+		t.Run(expression, func(t *testing.T) {
+			evaluable, err := expressions.New(expression)
+			assert.NoError(t, err)
+			_, err = evaluable.Evaluate(&expressions.EvaluationRequest{
+				Variables: map[string]interface{}{
+					"a": 7,
+					"s": "x",
+				},
+			})
+			assert.Error(t, err)
+			evaluationErr, ok := err.(*liberrors.Error)
+			assert.True(t, ok)
+			assert.Equal(t, expressions.ErrIncompatibleOperation, evaluationErr.Code)
+			assert.Equal(t, fmt.Sprintf("cannot apply '%v' operation on type 'number' and 'string' at position 2", expression[2:len(expression)-2]), evaluationErr.Msg)
+		})
+	}
+}
+
+func Test_LogicalOperands(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"a && s": {"a": true, "s": "x"},
+		"a || s": {"a": false, "s": "x"},
+	}
+	for expression, variables := range tests {
+		t.Run(expression, func(t *testing.T) {
+			evaluable, err := expressions.New(expression)
+			assert.NoError(t, err)
+			_, err = evaluable.Evaluate(&expressions.EvaluationRequest{
+				Variables: variables,
+			})
+			assert.Error(t, err)
+			evaluationErr, ok := err.(*liberrors.Error)
+			assert.True(t, ok)
+			assert.Equal(t, expressions.ErrIncompatibleOperation, evaluationErr.Code)
+			assert.Equal(t, fmt.Sprintf("cannot apply '%v' operation on type 'bool' and 'string' at position 2", expression[2:4]), evaluationErr.Msg)
+		})
+	}
+}
+
+func Test_FunctionErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		variables  map[string]interface{}
+		code       liberrors.ErrCode
+		msg        string
+	}{
+		{
+			name:       "invalid argument",
+			expression: "a + sqrt(b)",
+			variables: map[string]interface{}{
+				"a": 1,
+				"b": -4,
+			},
+			code: expressions.ErrInvalidArgument,
+			msg:  "sqrt of negative number -4 at position 4",
+		},
+		{
+			name:       "argument of a wrong type",
+			expression: "log(b)",
+			variables: map[string]interface{}{
+				"b": "x",
+			},
+			code: expressions.ErrIncompatibleOperation,
+			msg:  "function log expects number for argument 1, found string at position 0",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			evaluable, err := expressions.New(test.expression)
+			assert.NoError(t, err)
+			_, err = evaluable.Evaluate(&expressions.EvaluationRequest{
+				Variables: test.variables,
+			})
+			assert.Error(t, err)
+			evaluationErr, ok := err.(*liberrors.Error)
+			assert.True(t, ok)
+			assert.Equal(t, test.code, evaluationErr.Code)
+			assert.Equal(t, test.msg, evaluationErr.Msg)
+		})
+	}
+}
+
+func newTestRegistry(t *testing.T) *expressions.Registry {
+	registry := expressions.NewRegistry()
+	assert.NoError(t, registry.RegisterFunction(expressions.FunctionDefinition{
+		Name:     "concat",
+		Params:   []models.DataType{models.DataTypeString},
+		Variadic: true,
+		Result:   models.DataTypeString,
+		Call: func(args []models.Value) (models.Value, error) {
+			res := ""
+			for _, arg := range args {
+				res += *arg.String
+			}
+			return models.Value{String: &res}, nil
+		},
+	}))
+	assert.NoError(t, registry.RegisterFunction(expressions.FunctionDefinition{
+		Name:   "answer",
+		Result: models.DataTypeNumber,
+		Call: func(args []models.Value) (models.Value, error) {
+			return models.Value{Number: lib.Float64Ptr(42)}, nil
+		},
+	}))
+	assert.NoError(t, registry.RegisterOperator(expressions.OperatorDefinition{
+		Token:         "**",
+		Precedence:    6,
+		Associativity: expressions.RightAssociative,
+		Left:          models.DataTypeNumber,
+		Right:         models.DataTypeNumber,
+		Result:        models.DataTypeNumber,
+		Apply: func(left, right models.Value) (models.Value, error) {
+			return models.Value{Number: lib.Float64Ptr(math.Pow(*left.Number, *right.Number))}, nil
+		},
+	}))
+	assert.NoError(t, registry.RegisterOperator(expressions.OperatorDefinition{
+		Token:      "has",
+		Precedence: 2,
+		Left:       models.DataTypeList,
+		Right:      models.DataTypeUnknown,
+		Result:     models.DataTypeBool,
+		Apply: func(left, right models.Value) (models.Value, error) {
+			for _, elem := range left.List {
+				if reflect.DeepEqual(elem, right) {
+					return models.Value{Bool: lib.BoolPtr(true)}, nil
+				}
+			}
+			return models.Value{Bool: lib.BoolPtr(false)}, nil
+		},
+	}))
+	return registry
+}
+
+func Test_Registry(t *testing.T) {
+	registry := newTestRegistry(t)
+
+	tests := []struct {
+		name        string
+		expression  string
+		variables   map[string]interface{}
+		types       map[string]models.DataType
+		outputValue interface{}
+		outputType  models.DataType
+		err         error
+	}{
+		{
+			name:       "registry | variadic function",
+			expression: "concat(a, \"-\", b) == \"x-y\"",
+			variables: map[string]interface{}{
+				"a": "x",
+				"b": "y",
+			},
+			outputValue: true,
+			outputType:  models.DataTypeBool,
+		},
+		{
+			name:        "registry | function without arguments",
+			expression:  "answer() + 1",
+			outputValue: float64(43),
+			outputType:  models.DataTypeNumber,
+		},
+		{
+			name:       "registry | right associative operator",
+			expression: "a ** b ** c",
+			variables: map[string]interface{}{
+				"a": 2,
+				"b": 3,
+				"c": 2,
+			},
+			outputValue: float64(512),
+			outputType:  models.DataTypeNumber,
+		},
+		{
+			name:       "registry | operator precedence",
+			expression: "a ** 2 * 3 + max(a, 1)",
+			variables: map[string]interface{}{
+				"a": 2,
+			},
+			outputValue: float64(14),
+			outputType:  models.DataTypeNumber,
+		},
+		{
+			name:       "registry | operator of any type",
+			expression: "tags has \"vip\" && a > 1",
+			variables: map[string]interface{}{
+				"tags": []string{"new", "vip"},
+				"a":    2,
+			},
+			outputValue: true,
+			outputType:  models.DataTypeBool,
+		},
+		{
+			name:       "registry | operator with an operand of a wrong type",
+			expression: "a has 1",
+			types: map[string]models.DataType{
+				"a": models.DataTypeNumber,
+			},
+			err: errors.New("cannot apply 'has' operation on type 'number' and 'number' at position 2"),
+		},
+		{
+			name:       "registry | argument of a wrong type",
+			expression: "concat(a, 1)",
+			err:        errors.New("function concat expects string for argument 2, found number at position 0"),
+		},
+		{
+			name:       "registry | empty parenthesis",
+			expression: "a + ()",
+			err:        errors.New("empty parenthesis at position 4"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			evaluable, err := expressions.New(test.expression,
+				expressions.WithRegistry(registry),
+				expressions.WithVariableTypes(test.types))
+			if test.err != nil {
+				assert.Error(t, err)
+				assert.Equal(t, test.err.Error(), err.(*liberrors.Error).Msg)
+				return
+			}
+			assert.NoError(t, err)
+			res, err := evaluable.Evaluate(&expressions.EvaluationRequest{
+				Variables: test.variables,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, getExpectedResponse(test.outputType, test.outputValue), res)
+		})
+	}
+}
+
+func Test_RegistryErrors(t *testing.T) {
+	registry := newTestRegistry(t)
+	call := func(args []models.Value) (models.Value, error) {
+		return models.Value{}, nil
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		msg  string
+	}{
+		{
+			name: "builtin function",
+			err:  registry.RegisterFunction(expressions.FunctionDefinition{Name: "max", Result: models.DataTypeNumber, Call: call}),
+			msg:  "name 'max' has been used already",
+		},
+		{
+			name: "registered operator",
+			err:  registry.RegisterFunction(expressions.FunctionDefinition{Name: "has", Result: models.DataTypeBool, Call: call}),
+			msg:  "name 'has' has been used already",
+		},
+		{
+			name: "builtin operator name",
+			err:  registry.RegisterFunction(expressions.FunctionDefinition{Name: "in", Result: models.DataTypeBool, Call: call}),
+			msg:  "name 'in' has been used already",
+		},
+		{
+			name: "invalid name",
+			err:  registry.RegisterFunction(expressions.FunctionDefinition{Name: "a.b", Result: models.DataTypeBool, Call: call}),
+			msg:  "invalid function name 'a.b'",
+		},
+		{
+			name: "function without a result type",
+			err:  registry.RegisterFunction(expressions.FunctionDefinition{Name: "noop", Call: call}),
+			msg:  "function noop has invalid result type 'unknown'",
+		},
+		{
+			name: "builtin operator",
+			err:  registry.RegisterOperator(expressions.OperatorDefinition{Token: "&&", Result: models.DataTypeBool}),
+			msg:  "operator '&&' has been used already",
+		},
+		{
+			name: "operator without an implementation",
+			err:  registry.RegisterOperator(expressions.OperatorDefinition{Token: "<>", Result: models.DataTypeBool}),
+			msg:  "operator '<>' has no implementation",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Error(t, test.err)
+			registrationErr, ok := test.err.(*liberrors.Error)
+			assert.True(t, ok)
+			assert.Equal(t, expressions.ErrInvalidRegistration, registrationErr.Code)
+			assert.Equal(t, test.msg, registrationErr.Msg)
+		})
+	}
+}
+
+func Test_RegistryResultType(t *testing.T) {
+	registry := expressions.NewRegistry()
+	assert.NoError(t, registry.RegisterFunction(expressions.FunctionDefinition{
+		Name:   "bad",
+		Result: models.DataTypeNumber,
+		Call: func(args []models.Value) (models.Value, error) {
+			res := "x"
+			return models.Value{String: &res}, nil
+		},
+	}))
+
+	evaluable, err := expressions.New("bad() > 2", expressions.WithRegistry(registry))
+	assert.NoError(t, err)
+	_, err = evaluable.Evaluate(&expressions.EvaluationRequest{})
+	assert.Error(t, err)
+	evaluationErr, ok := err.(*liberrors.Error)
+	assert.True(t, ok)
+	assert.Equal(t, expressions.ErrIncompatibleOperation, evaluationErr.Code)
+	assert.Equal(t, "function bad returned a value that isn't of type number at position 0", evaluationErr.Msg)
+}
@@ -14,9 +14,14 @@ const (
 	Number
 	Bool
 	Operator
+	UnaryOperator
 	LeftParenthesis
 	RightParenthesis
 	KeyWord
+	Reference
+	List
+	Function
+	Comma
 	Eol
 	Unknown
 )
@@ -35,10 +40,20 @@ func (t TokenType) String() string {
 		return "Bool"
 	case Operator:
 		return "Operator"
+	case UnaryOperator:
+		return "UnaryOperator"
 	case LeftParenthesis:
 		return "LeftParenthesis"
 	case RightParenthesis:
 		return "RightParenthesis"
+	case Reference:
+		return "Reference"
+	case List:
+		return "List"
+	case Function:
+		return "Function"
+	case Comma:
+		return "Comma"
 	case Eol:
 		return "Eol"
 	default:
@@ -0,0 +1,151 @@
+package expressions
+
+import (
+	"fmt"
+
+	"github.com/anshal21/coffee-machine/lib/errors"
+	"github.com/anshal21/coffee-machine/lib/models"
+)
+
+// signature is a combination of operand types an operator accepts
+// along with the type of its result
+type signature struct {
+	left   models.DataType
+	right  models.DataType
+	result models.DataType
+}
+
+var (
+	_number = models.DataTypeNumber
+	_string = models.DataTypeString
+	_bool   = models.DataTypeBool
+	_list   = models.DataTypeList
+
+	// _operatorSignatures are the signatures of the built-in operators
+	_operatorSignatures = map[string][]signature{
+		"+":  {{_number, _number, _number}, {_string, _string, _string}},
+		"-":  {{_number, _number, _number}},
+		"*":  {{_number, _number, _number}},
+		"/":  {{_number, _number, _number}},
+		"//": {{_number, _number, _number}},
+		"%":  {{_number, _number, _number}},
+		"^":  {{_number, _number, _number}},
+		"<":  {{_number, _number, _bool}, {_string, _string, _bool}},
+		">":  {{_number, _number, _bool}, {_string, _string, _bool}},
+		"<=": {{_number, _number, _bool}, {_string, _string, _bool}},
+		">=": {{_number, _number, _bool}, {_string, _string, _bool}},
+		"==": {{_number, _number, _bool}, {_string, _string, _bool}, {_bool, _bool, _bool}},
+		"!=": {{_number, _number, _bool}, {_string, _string, _bool}, {_bool, _bool, _bool}},
+		"&&": {{_bool, _bool, _bool}},
+		"||": {{_bool, _bool, _bool}},
+		"in": {{_number, _list, _bool}, {_string, _list, _bool}, {_bool, _list, _bool}},
+	}
+
+	// _unaryOperatorSignatures are the signatures of the prefix operators
+	// the operand type is held by left
+	_unaryOperatorSignatures = map[string][]signature{
+		"!": {{left: _bool, result: _bool}},
+		"-": {{left: _number, result: _number}},
+		"+": {{left: _number, result: _number}},
+	}
+)
+
+// inferType infers the type of the expression rooted at curr given the types
+// of the variables, the expression is rejected if an operator can't be applied
+// on the types of its operands
+// DataTypeUnknown is inferred for the variables without a type and for the
+// user defined operators, such operations are checked only on evaluation
+func inferType(curr *node, types map[string]models.DataType) (models.DataType, error) {
+	switch curr.Token.Type {
+	case Variable:
+		return types[curr.Token.Value.(string)], nil
+	case String:
+		return models.DataTypeString, nil
+	case Number:
+		return models.DataTypeNumber, nil
+	case Bool:
+		return models.DataTypeBool, nil
+	case List:
+		return models.DataTypeList, nil
+	case Reference:
+		if curr.Target == nil {
+			return models.DataTypeUnknown, nil
+		}
+		return inferType(curr.Target, types)
+	case Function:
+		return callType(curr, types)
+	case UnaryOperator:
+		operand, err := inferType(curr.LeftChild, types)
+		if err != nil {
+			return models.DataTypeUnknown, err
+		}
+		for _, sig := range _unaryOperatorSignatures[curr.Token.Value.(string)] {
+			if accepts(sig.left, operand) {
+				return sig.result, nil
+			}
+		}
+		return models.DataTypeUnknown, errors.New(ErrIncompatibleOperation,
+			fmt.Errorf("cannot apply '%v' operation on type '%v' at position %v", curr.Token.Value, operand, curr.Token.Index))
+	case Operator:
+		if curr.Function != nil {
+			return callType(curr, types)
+		}
+		left, err := inferType(curr.LeftChild, types)
+		if err != nil {
+			return models.DataTypeUnknown, err
+		}
+		right, err := inferType(curr.RightChild, types)
+		if err != nil {
+			return models.DataTypeUnknown, err
+		}
+		return operationType(curr.Token, left, right)
+	}
+	return models.DataTypeUnknown, nil
+}
+
+// callType returns the type of the result of a function call or of a
+// registered operator after checking the types of its operands
+func callType(curr *node, types map[string]models.DataType) (models.DataType, error) {
+	argTypes := make([]models.DataType, 0, len(curr.Args)+2)
+	for _, operand := range operands(curr) {
+		argType, err := inferType(operand, types)
+		if err != nil {
+			return models.DataTypeUnknown, err
+		}
+		argTypes = append(argTypes, argType)
+	}
+	if err := checkArgumentTypes(curr, argTypes); err != nil {
+		return models.DataTypeUnknown, err
+	}
+	return curr.Function.result, nil
+}
+
+// operationType returns the type of the result of an operation, it is
+// unknown if the operands match more than one signature with different
+// result types
+func operationType(operator *Token, left, right models.DataType) (models.DataType, error) {
+	signatures, ok := _operatorSignatures[operator.Value.(string)]
+	if !ok {
+		return models.DataTypeUnknown, nil
+	}
+
+	result, matched := models.DataTypeUnknown, false
+	for _, sig := range signatures {
+		if !accepts(sig.left, left) || !accepts(sig.right, right) {
+			continue
+		}
+		if matched && result != sig.result {
+			return models.DataTypeUnknown, nil
+		}
+		result, matched = sig.result, true
+	}
+	if !matched {
+		return models.DataTypeUnknown, errors.New(ErrIncompatibleOperation,
+			fmt.Errorf("cannot apply '%v' operation on type '%v' and '%v' at position %v", operator.Value, left, right, operator.Index))
+	}
+	return result, nil
+}
+
+func accepts(expected, actual models.DataType) bool {
+	return actual == models.DataTypeUnknown || actual == expected
+}
@@ -8,9 +8,17 @@ type ErrCode string
 // Error is a custom error struct that explictly holds
 // and ErrCode to differentiate different type of errors
 type Error struct {
-	Code ErrCode
-	Msg  string
-	meta map[interface{}]interface{}
+	Code    ErrCode
+	Msg     string
+	Details []Detail `json:",omitempty"`
+	meta    map[interface{}]interface{}
+}
+
+// Detail describes one of the problems reported by an Error
+// Field is the name of the input the problem is with
+type Detail struct {
+	Field string
+	Msg   string
 }
 
 func (e *Error) Error() string {
@@ -31,3 +39,18 @@ func New(code ErrCode, err error, keyVals ...interface{}) *Error {
 		meta: meta,
 	}
 }
+
+// WithDetails adds the details of the problems to the error
+func (e *Error) WithDetails(details ...Detail) *Error {
+	e.Details = append(e.Details, details...)
+	return e
+}
+
+// Message returns the message of an error, without the code
+// for an Error and the complete text for any other error
+func Message(err error) string {
+	if e, ok := err.(*Error); ok {
+		return e.Msg
+	}
+	return err.Error()
+}
@@ -5,6 +5,29 @@ type Value struct {
 	Number *float64
 	String *string
 	Bool   *bool
+	List   []Value
+}
+
+// Interface returns the value held by v as a plain go value
+// i.e float64, string, bool or a []interface{} of them, it returns nil
+// for an empty value
+func (v Value) Interface() interface{} {
+	switch {
+	case v.List != nil:
+		list := make([]interface{}, 0, len(v.List))
+		for _, value := range v.List {
+			list = append(list, value.Interface())
+		}
+		return list
+	case v.Number != nil:
+		return *v.Number
+	case v.String != nil:
+		return *v.String
+	case v.Bool != nil:
+		return *v.Bool
+	default:
+		return nil
+	}
 }
 
 // DataType is a type to represent possible primitive data types in an expression
@@ -16,6 +39,7 @@ const (
 	DataTypeBool
 	DataTypeNumber
 	DataTypeString
+	DataTypeList
 )
 
 func (d DataType) String() string {
@@ -26,6 +50,8 @@ func (d DataType) String() string {
 		return "number"
 	case DataTypeString:
 		return "string"
+	case DataTypeList:
+		return "list"
 	default:
 		return "unknown"
 	}
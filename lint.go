@@ -0,0 +1,466 @@
+package coffeemachine
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/anshal21/coffee-machine/expressions"
+	"github.com/anshal21/coffee-machine/lib/errors"
+	"github.com/anshal21/coffee-machine/lib/models"
+)
+
+// Severity is the severity of a Diagnostic
+type Severity int
+
+// Set of severities of the diagnostics
+const (
+	// SeverityWarning is a problem that doesn't stop the rule-set from
+	// running but is most likely a mistake
+	SeverityWarning Severity = iota
+	// SeverityError is a problem that makes the rule-set invalid or makes
+	// it fail at run time
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Diagnostic is a problem found in a rule-set by Lint
+// RuleID is the rule the problem is with, if any, and Path is the JSON path
+// of the element of the rule-set e.g $.rules.R1.post_evals[1].id
+type Diagnostic struct {
+	Severity Severity
+	RuleID   string
+	Path     string
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%v: %v: %v", d.Severity, d.Path, d.Message)
+}
+
+// Lint checks the rule-set for problems and returns all of them, it reports
+// - the expressions that don't compile, every one on its own
+// - the other errors that make the rule-set invalid
+// - the predicates and constants that are never used
+// - the post-evals of a rule with the same id
+// - the relations that can never be followed and the rules they make unreachable
+// - the variables that are neither declared in the schema nor forwarded to a rule,
+// if the rule-set has a schema or the types of the variables are set with
+// WithVariableTypes
+// The options are the same as that of the parser
+func Lint(ruleSet io.Reader, options ...Option) []Diagnostic {
+	data, err := ioutil.ReadAll(ruleSet)
+	if err != nil {
+		return []Diagnostic{{Severity: SeverityError, Path: "$", Message: err.Error()}}
+	}
+
+	p := &parser{
+		config: newConfig(options...),
+	}
+	definition, locator, err := p.decode(data)
+	if err != nil {
+		return []Diagnostic{{Severity: SeverityError, Path: "$", Message: errors.Message(err)}}
+	}
+
+	l := &linter{
+		definition: definition,
+		lexer:      expressions.NewLexerWithRegistry(p.config.registry),
+	}
+	invalid := l.lintExpressions(p, locator)
+	// the parser stops at the first invalid expression, its error is
+	// reported only if it isn't about one of the expressions linted above
+	graph, err := p.Parse(bytes.NewReader(data))
+	if err != nil && !invalid {
+		l.report(SeverityError, "", "$", errors.Message(err))
+	}
+
+	l.lintReferences()
+	l.lintPostEvals()
+	if graph != nil {
+		l.lintRelations(graph, p.config)
+	}
+	if len(definition.Schema) > 0 || len(p.config.variableTypes) > 0 {
+		l.lintVariables(p.config.variableTypes)
+	}
+	return l.diagnostics
+}
+
+var (
+	_postEvalFields = []string{"post_evals", "on_false"}
+)
+
+type linter struct {
+	definition  *ruleSetDefinition
+	lexer       expressions.Lexer
+	diagnostics []Diagnostic
+}
+
+func (l *linter) report(severity Severity, ruleID, path, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Severity: severity,
+		RuleID:   ruleID,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// lintExpressions compiles the expressions of the rules and the relations
+// one by one and reports the ones that are invalid along with the predicates
+// and conditions that aren't boolean, it tells if any problem was reported
+func (l *linter) lintExpressions(p *parser, locator Locator) bool {
+	constants, err := parseConstants(locator, l.definition.Constants)
+	if err != nil {
+		return false
+	}
+	schema, err := parseSchema(locator, l.definition.Schema)
+	if err != nil {
+		return false
+	}
+	compiler := newCompiler(l.definition.Predicates, constants, p.variableTypes(schema), p.config.registry)
+
+	invalid := false
+	for _, expr := range l.expressions() {
+		compiled, err := compiler.compile(expr.text)
+		switch {
+		case err != nil:
+			l.report(SeverityError, expr.ruleID, expr.path, "%v has invalid %v, %v", expr.owner, expr.field, errors.Message(err))
+		case expr.boolean && !isBoolean(compiled):
+			l.report(SeverityError, expr.ruleID, expr.path, "%v has a %v of type %v, it should be bool", expr.owner, expr.field, compiled.Type())
+		default:
+			continue
+		}
+		invalid = true
+	}
+	return invalid
+}
+
+// lintReferences reports the predicates and constants that are not used
+// by any rule or relation, directly or through other predicates
+func (l *linter) lintReferences() {
+	usedPredicates := make(map[string]bool)
+	usedConstants := make(map[string]bool)
+
+	var use func(expr string)
+	use = func(expr string) {
+		_, references := l.scanExpression(expr)
+		for _, reference := range references {
+			parts := strings.SplitN(reference, ":", 2)
+			switch parts[0] {
+			case _constantNamespace:
+				usedConstants[parts[1]] = true
+			case _predicateNamespace:
+				if usedPredicates[parts[1]] {
+					continue
+				}
+				usedPredicates[parts[1]] = true
+				use(l.definition.Predicates[parts[1]])
+			}
+		}
+	}
+	for _, expr := range l.expressions() {
+		use(expr.text)
+	}
+
+	predicates := make([]string, 0, len(l.definition.Predicates))
+	for id := range l.definition.Predicates {
+		predicates = append(predicates, id)
+	}
+	sort.Strings(predicates)
+	for _, id := range predicates {
+		if !usedPredicates[id] {
+			l.report(SeverityWarning, "", "$.predicates."+id, "predicate %v is never used", id)
+		}
+	}
+	constants := make([]string, 0, len(l.definition.Constants))
+	for name := range l.definition.Constants {
+		constants = append(constants, name)
+	}
+	sort.Strings(constants)
+	for _, name := range constants {
+		if !usedConstants[name] {
+			l.report(SeverityWarning, "", "$.constants."+name, "constant %v is never used", name)
+		}
+	}
+}
+
+// lintPostEvals reports the post-evals of a rule that reuse an id, only
+// one of them makes it to the output
+func (l *linter) lintPostEvals() {
+	for _, rule := range l.definition.Rules {
+		for _, field := range _postEvalFields {
+			postEvals := rule.postEvals(field)
+			seen := make(map[string]bool, len(postEvals))
+			for index, postEval := range postEvals {
+				if seen[postEval.ID] {
+					l.report(SeverityError, rule.ID, fmt.Sprintf("$.rules.%v.%v[%v].id", rule.ID, field, index),
+						"rule %v has more than one %v with id %v", rule.ID, field, postEval.ID)
+				}
+				seen[postEval.ID] = true
+			}
+		}
+	}
+}
+
+// lintRelations reports the relations that can never be followed, either
+// because the parent rule is never evaluated or because its predicate is
+// a constant that doesn't match the when of the relation, along with the
+// rules that can never be evaluated as a result
+func (l *linter) lintRelations(graph *RuleGraph, c *config) {
+	now := c.clock()
+	dead := make(map[*Node]string)
+	deadEdges := make(map[*Edge]string)
+
+	for _, node := range graph.ExecutionOrder {
+		rule := node.Rule
+		if !rule.Enabled {
+			dead[node] = fmt.Sprintf("rule %v is disabled", rule.ID)
+			continue
+		}
+		if !rule.ValidUntil.IsZero() && !now.Before(rule.ValidUntil) {
+			dead[node] = fmt.Sprintf("rule %v has expired", rule.ID)
+			continue
+		}
+		if len(node.Incoming) == 0 {
+			continue
+		}
+
+		live := 0
+		for _, edge := range node.Incoming {
+			if reason, ok := dead[edge.Source]; ok {
+				deadEdges[edge] = reason
+				continue
+			}
+			if value, ok := constantPredicate(edge.Source.Rule.Predicate); ok && value != edge.When {
+				deadEdges[edge] = fmt.Sprintf("rule %v always evaluates to %v", edge.Source.Rule.ID, value)
+				continue
+			}
+			live++
+		}
+
+		required := 1
+		switch rule.Join {
+		case JoinAll:
+			required = len(node.Incoming)
+		case JoinNOfM:
+			required = rule.JoinCount
+		}
+		if live < required {
+			dead[node] = fmt.Sprintf("rule %v can never be reached", rule.ID)
+			l.report(SeverityWarning, rule.ID, "$.rules."+rule.ID,
+				"rule %v can never be evaluated, not enough of its relations can be followed", rule.ID)
+		}
+	}
+
+	nodes := make(map[string]*Node, len(graph.ExecutionOrder))
+	for _, node := range graph.ExecutionOrder {
+		nodes[node.Rule.ID] = node
+	}
+	for index, relation := range l.definition.Relations {
+		when := relation.When == nil || *relation.When
+		for _, edge := range nodes[relation.From].Relations {
+			if edge.Destination.Rule.ID != relation.To || edge.When != when {
+				continue
+			}
+			if reason, ok := deadEdges[edge]; ok {
+				l.report(SeverityWarning, relation.From, fmt.Sprintf("$.relations[%v]", index),
+					"relation from %v to %v can never be followed, %v", relation.From, relation.To, reason)
+			}
+		}
+	}
+}
+
+// lintVariables reports the variables used by the expressions of a rule that
+// are neither declared in the schema or the configured types, nor forwarded
+// to the rule by its relations
+func (l *linter) lintVariables(types map[string]models.DataType) {
+	declared := make(map[string]bool, len(types)+len(l.definition.Schema))
+	for name := range types {
+		declared[name] = true
+	}
+	for name := range l.definition.Schema {
+		declared[name] = true
+	}
+
+	rules := make(map[string]*ruleDefinition, len(l.definition.Rules))
+	for _, rule := range l.definition.Rules {
+		rules[rule.ID] = rule
+	}
+	forwarded := make(map[string]map[string]bool, len(rules))
+	for _, relation := range l.definition.Relations {
+		source, ok := rules[relation.From]
+		if !relation.ForwardOutput || !ok {
+			continue
+		}
+		if forwarded[relation.To] == nil {
+			forwarded[relation.To] = make(map[string]bool)
+		}
+		for _, name := range outputVariables(source) {
+			forwarded[relation.To][name] = true
+		}
+	}
+
+	for _, expr := range l.expressions() {
+		available := forwarded[expr.ruleID]
+		if expr.relation != nil && expr.relation.ForwardOutput {
+			available = make(map[string]bool)
+			for name := range forwarded[expr.ruleID] {
+				available[name] = true
+			}
+			if source, ok := rules[expr.ruleID]; ok {
+				for _, name := range outputVariables(source) {
+					available[name] = true
+				}
+			}
+		}
+		for _, variable := range l.variables(expr.text) {
+			if declared[variable] || available[variable] {
+				continue
+			}
+			l.report(SeverityError, expr.ruleID, expr.path, "%v uses undefined variable %v", expr.owner, variable)
+		}
+	}
+}
+
+// variables returns the variables used by an expression, including the
+// ones used by the predicates it refers to, in the order of appearance
+func (l *linter) variables(expr string) []string {
+	variables := make([]string, 0)
+	seen := make(map[string]bool)
+	visited := make(map[string]bool)
+
+	var collect func(expr string)
+	collect = func(expr string) {
+		names, references := l.scanExpression(expr)
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				variables = append(variables, name)
+			}
+		}
+		for _, reference := range references {
+			parts := strings.SplitN(reference, ":", 2)
+			if parts[0] != _predicateNamespace || visited[parts[1]] {
+				continue
+			}
+			visited[parts[1]] = true
+			collect(l.definition.Predicates[parts[1]])
+		}
+	}
+	collect(expr)
+	return variables
+}
+
+// lintedExpression is an expression of the rule-set along with where it is
+// used, ruleID is the source rule for a relation condition and field is
+// what the expression is to its owner e.g predicate
+// boolean is set for the expressions that should evaluate to a bool
+type lintedExpression struct {
+	text     string
+	ruleID   string
+	owner    string
+	field    string
+	path     string
+	boolean  bool
+	relation *relationDefinition
+}
+
+// expressions returns the expressions used by the rules and the relations
+func (l *linter) expressions() []lintedExpression {
+	exprs := make([]lintedExpression, 0)
+	for _, rule := range l.definition.Rules {
+		owner := "rule " + rule.ID
+		exprs = append(exprs, lintedExpression{
+			text:    rule.Predicate,
+			ruleID:  rule.ID,
+			owner:   owner,
+			field:   "predicate",
+			path:    fmt.Sprintf("$.rules.%v.predicate", rule.ID),
+			boolean: true,
+		})
+		for _, field := range _postEvalFields {
+			for index, postEval := range rule.postEvals(field) {
+				if postEval.Type != OutputTypeExpression {
+					continue
+				}
+				exprs = append(exprs, lintedExpression{
+					text:   postEval.Value,
+					ruleID: rule.ID,
+					owner:  owner,
+					field:  "predicate for output " + postEval.ID,
+					path:   fmt.Sprintf("$.rules.%v.%v[%v].value", rule.ID, field, index),
+				})
+			}
+		}
+	}
+	for index := range l.definition.Relations {
+		relation := &l.definition.Relations[index]
+		if relation.Condition == "" {
+			continue
+		}
+		exprs = append(exprs, lintedExpression{
+			text:     relation.Condition,
+			ruleID:   relation.From,
+			owner:    fmt.Sprintf("relation from %v to %v", relation.From, relation.To),
+			field:    "condition",
+			path:     fmt.Sprintf("$.relations[%v].condition", index),
+			boolean:  true,
+			relation: relation,
+		})
+	}
+	return exprs
+}
+
+// scanExpression returns the variables and the references used by an
+// expression, nothing is returned for an expression that can't be lexed
+func (l *linter) scanExpression(expr string) ([]string, []string) {
+	tokens, err := l.lexer.Lex(expr)
+	if err != nil {
+		return nil, nil
+	}
+	variables := make([]string, 0)
+	references := make([]string, 0)
+	for _, token := range tokens {
+		switch token.Type {
+		case expressions.Variable:
+			variables = append(variables, token.Value.(string))
+		case expressions.Reference:
+			references = append(references, token.Value.(string))
+		}
+	}
+	return variables, references
+}
+
+// outputVariables returns the names of the variables a rule forwards
+// to its children e.g R1.output_1
+func outputVariables(rule *ruleDefinition) []string {
+	names := make([]string, 0, len(rule.PostEvals)+len(rule.OnFalse))
+	for _, field := range _postEvalFields {
+		for _, postEval := range rule.postEvals(field) {
+			names = append(names, rule.ID+"."+postEval.ID)
+		}
+	}
+	return names
+}
+
+// constantPredicate tells if a predicate doesn't depend on any variable,
+// along with the value it always evaluates to
+func constantPredicate(predicate expressions.Expression) (bool, bool) {
+	res, err := predicate.Evaluate(&expressions.EvaluationRequest{})
+	if err != nil || res.Type != models.DataTypeBool {
+		return false, false
+	}
+	return *res.Value.Bool, true
+}
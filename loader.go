@@ -0,0 +1,196 @@
+package coffeemachine
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/anshal21/coffee-machine/lib/errors"
+)
+
+// Loader is an interface to fetch a rule-set from its source
+// It is used by the Watcher to detect and load the changes
+// in the rule-set
+type Loader interface {
+	// Load returns a reader for the current version of the rule-set
+	Load() (io.Reader, error)
+	// Version returns an identifier for the current version of the
+	// rule-set, the identifier changes whenever the rule-set changes
+	Version() (string, error)
+}
+
+// NewFileLoader is a constructor for a Loader that reads the
+// rule-set from a file, the version of the rule-set is derived
+// from the modification time and the size of the file
+func NewFileLoader(path string) Loader {
+	return &fileLoader{
+		path: path,
+	}
+}
+
+type fileLoader struct {
+	path string
+}
+
+func (f *fileLoader) Load() (io.Reader, error) {
+	return readFile(f.path)
+}
+
+func (f *fileLoader) Version() (string, error) {
+	return fileVersion(f.path)
+}
+
+// NewDirectoryLoader is a constructor for a Loader that reads the rule-set
+// from a directory holding versioned copies of a rule-set
+// The files in the directory that match the glob pattern e.g "ruleset-*.json"
+// are sorted by name and the last one is treated as the current rule-set, so
+// a new version can be rolled out by adding a file to the directory
+// The numbers in the names are compared by value i.e ruleset-10.json comes
+// after ruleset-9.json
+func NewDirectoryLoader(dir string, pattern string) Loader {
+	return &directoryLoader{
+		dir:     dir,
+		pattern: pattern,
+	}
+}
+
+type directoryLoader struct {
+	dir     string
+	pattern string
+}
+
+func (d *directoryLoader) Load() (io.Reader, error) {
+	path, err := d.latest()
+	if err != nil {
+		return nil, err
+	}
+	return readFile(path)
+}
+
+func (d *directoryLoader) Version() (string, error) {
+	path, err := d.latest()
+	if err != nil {
+		return "", err
+	}
+	version, err := fileVersion(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(path) + ":" + version, nil
+}
+
+func (d *directoryLoader) latest() (string, error) {
+	paths, err := filepath.Glob(filepath.Join(d.dir, d.pattern))
+	if err != nil {
+		return "", errors.New(ErrRuleSetNotFound, err)
+	}
+	if len(paths) == 0 {
+		return "", errors.New(ErrRuleSetNotFound, fmt.Errorf("no rule-set matching %v in directory %v", d.pattern, d.dir))
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return naturalLess(filepath.Base(paths[i]), filepath.Base(paths[j]))
+	})
+	return paths[len(paths)-1], nil
+}
+
+// naturalLess compares the names with the runs of digits compared by their
+// numeric value and the rest compared as it is
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		numberA, restA := splitDigits(a)
+		numberB, restB := splitDigits(b)
+		switch {
+		case numberA != "" && numberB != "":
+			trimmedA, trimmedB := strings.TrimLeft(numberA, "0"), strings.TrimLeft(numberB, "0")
+			if len(trimmedA) != len(trimmedB) {
+				return len(trimmedA) < len(trimmedB)
+			}
+			if trimmedA != trimmedB {
+				return trimmedA < trimmedB
+			}
+			a, b = restA, restB
+		case a[0] != b[0]:
+			return a[0] < b[0]
+		default:
+			a, b = a[1:], b[1:]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// splitDigits splits the leading digits of s from the rest
+func splitDigits(s string) (string, string) {
+	index := 0
+	for index < len(s) && s[index] >= '0' && s[index] <= '9' {
+		index++
+	}
+	return s[:index], s[index:]
+}
+
+// MemoryLoader is a Loader that holds the rule-set in memory
+// The rule-set can be replaced using the Update method
+type MemoryLoader interface {
+	Loader
+	Update(ruleSet []byte)
+}
+
+// NewMemoryLoader is a constructor for MemoryLoader
+// It takes the initial rule-set as an input
+func NewMemoryLoader(ruleSet []byte) MemoryLoader {
+	return &memoryLoader{
+		ruleSet: ruleSet,
+	}
+}
+
+type memoryLoader struct {
+	mu      sync.RWMutex
+	ruleSet []byte
+	version int
+}
+
+func (m *memoryLoader) Load() (io.Reader, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return bytes.NewReader(m.ruleSet), nil
+}
+
+func (m *memoryLoader) Version() (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return strconv.Itoa(m.version), nil
+}
+
+func (m *memoryLoader) Update(ruleSet []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ruleSet = ruleSet
+	m.version++
+}
+
+func readFile(path string) (io.Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New(ErrRuleSetNotFound, err)
+	}
+	defer f.Close()
+
+	buf := &bytes.Buffer{}
+	if _, err := buf.ReadFrom(f); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func fileVersion(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", errors.New(ErrRuleSetNotFound, err)
+	}
+	return fmt.Sprintf("%v-%v", info.ModTime().UnixNano(), info.Size()), nil
+}
@@ -1,6 +1,8 @@
 package coffeemachine
 
 import (
+	"time"
+
 	"github.com/anshal21/coffee-machine/expressions"
 	"github.com/anshal21/coffee-machine/lib/models"
 )
@@ -10,14 +12,60 @@ const (
 	OutputTypeConstant   = "CONST"
 )
 
+// Set of join modes for the rules with more than one parent
+const (
+	// JoinAny evaluates the rule if any of the relations is followed
+	JoinAny = "any"
+	// JoinAll evaluates the rule only if all the relations are followed
+	JoinAll = "all"
+	// JoinNOfM evaluates the rule if at least JoinCount relations are followed
+	JoinNOfM = "n_of_m"
+)
+
 // Rule is an in-memory represent of a rule
 // defined in the rule-set
 // It consists of rule-id, associated predicate
-// ans a list of post-evals
+// ans a list of post-evals, OnFalse is the list
+// of post-evals calculated if the predicate
+// evaluates to false
+// Order is used to decide the execution order
+// among the rules that don't depend on each other
+// and Priority is used by the conflict-resolution
+// strategies to pick among the rules that match
+// Join decides how the relations from the parent
+// rules are combined, it is one of JoinAny, JoinAll
+// and JoinNOfM
+// A rule that is not enabled or is out of its validity
+// window is skipped by the evaluator, a zero ValidFrom
+// or ValidUntil leaves the window open on that side
 type Rule struct {
-	ID        string
-	Predicate expressions.Expression
-	PostEvals []*RulePostEval
+	ID          string
+	Description string
+	Tags        []string
+	Enabled     bool
+	ValidFrom   time.Time
+	ValidUntil  time.Time
+	Order       int
+	Priority    int
+	Join        string
+	JoinCount   int
+	Predicate   expressions.Expression
+	PostEvals   []*RulePostEval
+	OnFalse     []*RulePostEval
+}
+
+// IsActive tells if the rule has to be evaluated at the given time
+func (r *Rule) IsActive(now time.Time) bool {
+	if !r.Enabled {
+		return false
+	}
+	if !r.ValidFrom.IsZero() && now.Before(r.ValidFrom) {
+		return false
+	}
+	if !r.ValidUntil.IsZero() && !now.Before(r.ValidUntil) {
+		return false
+	}
+	return true
 }
 
 // RulePostEval is an expression or constant
@@ -36,24 +84,58 @@ type RulePostEval struct {
 // Node represents a node in the rule-graph
 // it consists of a rule and any edges / relations
 // with other rules
+// Relations holds the outgoing edges and Incoming holds
+// the edges from the rules this node depends on
 type Node struct {
 	Rule      *Rule
 	Relations []*Edge
+	Incoming  []*Edge
+	// position is the index of the rule in the rule-set
+	// declaration
+	position int
 }
 
 // Edge is a struct to represent a relation
 // between two rules in the dependency graph
+// The edge is followed only if the predicate of
+// the source rule evaluates to the value of When
+// and the Condition, if any, evaluates to true
 type Edge struct {
+	Source        *Node
 	Destination   *Node
 	ForwardOutput bool
+	When          bool
+	Condition     expressions.Expression
 }
 
 // RuleGraph is a dependency graph representation
 // of the rule-set
+// ExecutionOrder is a topological ordering of the rule
+// nodes, it is computed once while parsing and every
+// node appears after all the nodes it depends on
+// The rules that don't depend on each other are ordered
+// by the order field of the rule and then by the order
+// of declaration in the rule-set
+// Constants holds the constants declared in the rule-set
+// the numbers are float64 and the lists are []interface{}
+// Schema holds the variables declared in the rule-set, sorted
+// by name
 type RuleGraph struct {
-	ID        string
-	Root      *Node
-	Constants []interface{}
+	ID             string
+	Root           *Node
+	ExecutionOrder []*Node
+	Constants      map[string]interface{}
+	Schema         []*Variable
+}
+
+// Variable is the declaration of a variable in the schema of a rule-set
+// A variable that is not required takes the Default value, if any, when
+// it is missing in the request
+type Variable struct {
+	Name     string
+	Type     models.DataType
+	Required bool
+	Default  interface{}
 }
 
 // RuleEngineRequest is a struct that holds
@@ -70,7 +152,24 @@ type RuleEngineRequest struct {
 	// evaluated to true
 	EvaluatedTrueCount bool
 	// EvaluatedRules, If true, output contains the rule-ids of the evaluated rules
+	// in the order of execution, along with the rule-ids of the rules that
+	// evaluated to false
 	EvaluatedRules bool
+	// Strategy is the conflict-resolution strategy used to pick the outputs
+	// from the rules that evaluate to true, defaults to StrategyAllMatches
+	Strategy Strategy
+	// Limit is the number of outputs returned with StrategyTopN
+	Limit int
+	// IncludeRules and IncludeTags, If set, only the rules with one of the
+	// ids or with at least one of the tags are run, all rules are run otherwise
+	IncludeRules []string
+	IncludeTags  []string
+	// ExcludeRules and ExcludeTags, If set, the rules with one of the ids or
+	// with at least one of the tags are not run, exclusion wins over inclusion
+	// The parents of a rule that is run are always evaluated, so that the
+	// relations hold, but their outputs are not returned unless selected
+	ExcludeRules []string
+	ExcludeTags  []string
 }
 
 // EvaluationOutput contains evaluation output for a expression
@@ -84,16 +183,22 @@ type EvaluationOutput struct {
 // RuleOutput is a struct to hold the result of a rule evaluation
 // It contains the RuleID and evaluation response for all
 // the associated post-evals
+// OnFalse is set if the rule evaluated to false and the
+// PostEvals are the on_false post-evals of the rule
 type RuleOutput struct {
 	ID        string
 	PostEvals []*EvaluationOutput
+	OnFalse   bool
 }
 
 // RuleEngineResponse is a struct that holds the response for a
 // rule-engine Run
+// RulesEvaluated, RulesEvaluatedTrue, EvaluatedRules and EvaluatedFalseRules
+// are populated only if requested in the RuleEngineRequest
 type RuleEngineResponse struct {
-	RulesEvaluated     int
-	RulesEvaluatedTrue int
-	Outputs            []*RuleOutput
-	EvaluatedRules     []string
+	RulesEvaluated      int
+	RulesEvaluatedTrue  int
+	Outputs             []*RuleOutput
+	EvaluatedRules      []string
+	EvaluatedFalseRules []string
 }
@@ -0,0 +1,87 @@
+package coffeemachine
+
+import (
+	"time"
+
+	"github.com/anshal21/coffee-machine/expressions"
+	"github.com/anshal21/coffee-machine/lib/models"
+)
+
+// Option represent an option type to override the default behaviour of
+// the parser, the evaluator and the rule-engine
+type Option func(c *config)
+
+type config struct {
+	format        string
+	decoders      map[string]Decoder
+	clock         func() time.Time
+	variableTypes map[string]models.DataType
+	registry      *expressions.Registry
+}
+
+func newConfig(options ...Option) *config {
+	c := &config{
+		clock: time.Now,
+		decoders: map[string]Decoder{
+			FormatJSON: NewJSONDecoder(),
+			FormatYAML: NewYAMLDecoder(),
+		},
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// WithFormat sets the format of the rule-set e.g FormatJSON or FormatYAML
+// By default the format is sniffed from the content of the rule-set
+func WithFormat(format string) Option {
+	return func(c *config) {
+		c.format = format
+	}
+}
+
+// WithDecoder registers a decoder for a rule-set format, it can be used
+// to plug-in a new format or to override the decoder of an existing one
+func WithDecoder(format string, decoder Decoder) Option {
+	return func(c *config) {
+		c.decoders[format] = decoder
+	}
+}
+
+// WithClock sets the clock used by the evaluator to check the validity
+// window of the rules, it defaults to time.Now
+func WithClock(clock func() time.Time) Option {
+	return func(c *config) {
+		c.clock = clock
+	}
+}
+
+// WithVariableTypes sets the types of the variables used in the rule-set
+// The expressions are type checked with these types while parsing, along
+// with the types declared in the schema of the rule-set which take precedence
+func WithVariableTypes(types map[string]models.DataType) Option {
+	return func(c *config) {
+		c.variableTypes = types
+	}
+}
+
+// WithStrictDecoding makes the parser reject a rule-set with unknown fields,
+// duplicate keys or values of a wrong type instead of ignoring them, it sets
+// the strict decoders for JSON and YAML, a decoder registered with WithDecoder
+// after it takes precedence
+func WithStrictDecoding() Option {
+	return func(c *config) {
+		c.decoders[FormatJSON] = NewStrictJSONDecoder()
+		c.decoders[FormatYAML] = NewStrictYAMLDecoder()
+	}
+}
+
+// WithRegistry sets the registry of the functions and the operators defined
+// by the callers, they can be used in the predicates and the post evals of
+// the rule-set along with the builtin ones
+func WithRegistry(registry *expressions.Registry) Option {
+	return func(c *config) {
+		c.registry = registry
+	}
+}
@@ -1,14 +1,16 @@
 package coffeemachine
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"strings"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/anshal21/coffee-machine/expressions"
 	"github.com/anshal21/coffee-machine/lib/errors"
+	"github.com/anshal21/coffee-machine/lib/models"
 )
 
 const (
@@ -27,14 +29,22 @@ type Parser interface {
 }
 
 // NewParser is a constructor for Parser
-func NewParser() Parser {
-	return &parser{}
+// The format of the rule-set is sniffed from its content unless it is set
+// using WithFormat, decoders for new formats can be added using WithDecoder
+func NewParser(options ...Option) Parser {
+	return &parser{
+		config: newConfig(options...),
+	}
 }
 
-type parser struct{}
+type parser struct {
+	config *config
+}
 
 // Parse function takes a rule-set as input and generates
 // a rule-graph for the same
+// The rule-set can be a JSON or a YAML document, or of any other
+// format registered with the parser
 // Sample format for the rule-graph is as follows, please read
 // README.md for more details
 // {
@@ -52,7 +62,7 @@ type parser struct{}
 // 					"value": "a + b"
 // 				},
 // 				{
-// 					"id": "output_1",
+// 					"id": "output_2",
 // 					"type": "CONST",
 // 					"value": "action_1"
 // 				}
@@ -61,90 +71,87 @@ type parser struct{}
 // 	}
 // }
 func (p *parser) Parse(reader io.Reader) (*RuleGraph, error) {
-	data := struct {
-		ID         string            `json:"id"`
-		Predicates map[string]string `json:"predicates`
-		Rules      map[string]struct {
-			Predicate string `json:"predicate"`
-			PostEvals []struct {
-				ID    string `json:"id"`
-				Type  string `json:"type"`
-				Value string `json:"value"`
-				Echo  bool   `json:"echo"`
-			} `json:"post_evals"`
-		} `json:"rules"`
-		Relations []struct {
-			From          string `json:"from"`
-			To            string `json:"to"`
-			ForwardOutput bool   `json:"forward_output"`
-		} `json:"relations"`
-	}{}
-
 	bytes, err := ioutil.ReadAll(reader)
 	if err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(bytes, &data)
+
+	data, locator, err := p.decode(bytes)
 	if err != nil {
 		return nil, err
 	}
 
 	rulesIDToNode := make(map[string]*Node)
+	nodes := make([]*Node, 0, len(data.Rules))
 	indegree := make(map[*Node]int)
 
-	for ruleID, ruleDef := range data.Rules {
+	constants, err := parseConstants(locator, data.Constants)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := parseSchema(locator, data.Schema)
+	if err != nil {
+		return nil, err
+	}
+	compiler := newCompiler(data.Predicates, constants, p.variableTypes(schema), p.config.registry)
+
+	for _, ruleDef := range data.Rules {
+		ruleID := ruleDef.ID
 		if _, ok := rulesIDToNode[ruleID]; ok {
-			return nil, errors.New(ErrInvalidRuleSet, fmt.Errorf("rule id %v has been used already", ruleID))
+			return nil, invalidRuleSet(locator, fmt.Errorf("rule id %v has been used already", ruleID), "rules", ruleID)
+		}
+		postEvals, err := parsePostEvals(compiler, locator, ruleID, "post_evals", ruleDef.PostEvals)
+		if err != nil {
+			return nil, err
+		}
+		onFalse, err := parsePostEvals(compiler, locator, ruleID, "on_false", ruleDef.OnFalse)
+		if err != nil {
+			return nil, err
 		}
-		postEvals := make([]*RulePostEval, 0)
-		for _, postEval := range ruleDef.PostEvals {
-
-			output := &RulePostEval{
-				ID:   postEval.ID,
-				Type: postEval.Type,
-				Echo: postEval.Echo,
-			}
 
-			switch postEval.Type {
-			case OutputTypeExpression:
-				predicate, err := resolvePredicate(data.Predicates, postEval.Value)
-				if err != nil {
-					return nil, errors.New(ErrInvalidRuleSet, err)
-				}
-				expr, err := expressions.New(predicate)
-				if err != nil {
-					return nil, errors.New(ErrInvalidRuleSet, fmt.Errorf("rule %v has invalid predicate for output %v, %v", ruleID, postEval.ID, err.Error()))
-				}
-				output.Evaluable = expr
-
-			case OutputTypeConstant:
-				output.Const = postEval.Value
-
-			default:
-				return nil, errors.New(ErrInvalidRuleSet, fmt.Errorf("invalid output type used for output %v in rule %v", postEval.ID, ruleID))
-			}
-			postEvals = append(postEvals, output)
+		expr, err := compiler.compile(ruleDef.Predicate)
+		if err != nil {
+			return nil, invalidRuleSet(locator, fmt.Errorf("rule %v has invalid predicate, %v", ruleID, errors.Message(err)), "rules", ruleID, "predicate")
+		}
+		if !isBoolean(expr) {
+			return nil, invalidRuleSet(locator, fmt.Errorf("rule %v has a predicate of type %v, it should be bool", ruleID, expr.Type()), "rules", ruleID, "predicate")
 		}
 
-		predicate, err := resolvePredicate(data.Predicates, ruleDef.Predicate)
+		validFrom, err := parseTimestamp(ruleDef.ValidFrom)
 		if err != nil {
-			return nil, errors.New(ErrInvalidRuleSet, err)
+			return nil, invalidRuleSet(locator, fmt.Errorf("rule %v has invalid valid_from, %v", ruleID, err.Error()), "rules", ruleID, "valid_from")
 		}
-		expr, err := expressions.New(predicate)
+		validUntil, err := parseTimestamp(ruleDef.ValidUntil)
 		if err != nil {
-			return nil, errors.New(ErrInvalidRuleSet, fmt.Errorf("rule %v has invalid predicate, %v", ruleID, err.Error()))
+			return nil, invalidRuleSet(locator, fmt.Errorf("rule %v has invalid valid_until, %v", ruleID, err.Error()), "rules", ruleID, "valid_until")
+		}
+		if !validFrom.IsZero() && !validUntil.IsZero() && !validFrom.Before(validUntil) {
+			return nil, invalidRuleSet(locator, fmt.Errorf("rule %v has valid_from after valid_until", ruleID), "rules", ruleID, "valid_until")
 		}
 
 		rule := &Rule{
-			ID:        ruleID,
-			Predicate: expr,
-			PostEvals: postEvals,
+			ID:          ruleID,
+			Description: ruleDef.Description,
+			Tags:        ruleDef.Tags,
+			Enabled:     ruleDef.Enabled == nil || *ruleDef.Enabled,
+			ValidFrom:   validFrom,
+			ValidUntil:  validUntil,
+			Order:       ruleDef.Order,
+			Priority:    ruleDef.Priority,
+			Join:        ruleDef.Join,
+			JoinCount:   ruleDef.JoinCount,
+			Predicate:   expr,
+			PostEvals:   postEvals,
+			OnFalse:     onFalse,
 		}
 
-		rulesIDToNode[ruleID] = &Node{
-			Rule: rule,
+		node := &Node{
+			Rule:     rule,
+			position: len(nodes),
 		}
-		indegree[rulesIDToNode[ruleID]] = 0
+		rulesIDToNode[ruleID] = node
+		nodes = append(nodes, node)
+		indegree[node] = 0
 	}
 
 	rootNode := &Node{
@@ -154,65 +161,230 @@ func (p *parser) Parse(reader io.Reader) (*RuleGraph, error) {
 		},
 	}
 
-	for _, relation := range data.Relations {
+	for index, relation := range data.Relations {
 		if _, ok := rulesIDToNode[relation.From]; !ok {
-			return nil, errors.New(ErrInvalidRuleSet, fmt.Errorf("invalid rule id %v used for relation", relation.From))
+			return nil, invalidRuleSet(locator, fmt.Errorf("invalid rule id %v used for relation", relation.From), "relations", strconv.Itoa(index), "from")
 		}
 		if _, ok := rulesIDToNode[relation.To]; !ok {
-			return nil, errors.New(ErrInvalidRuleSet, fmt.Errorf("invalid rule id %v used for relation", relation.To))
+			return nil, invalidRuleSet(locator, fmt.Errorf("invalid rule id %v used for relation", relation.To), "relations", strconv.Itoa(index), "to")
 		}
 
 		fromNode := rulesIDToNode[relation.From]
 		toNode := rulesIDToNode[relation.To]
-		fromNode.Relations = append(fromNode.Relations, &Edge{
+		edge := &Edge{
+			Source:        fromNode,
 			Destination:   toNode,
 			ForwardOutput: relation.ForwardOutput,
-		})
+			When:          relation.When == nil || *relation.When,
+		}
+		if relation.Condition != "" {
+			expr, err := compiler.compile(relation.Condition)
+			if err != nil {
+				return nil, invalidRuleSet(locator, fmt.Errorf("relation from %v to %v has invalid condition, %v", relation.From, relation.To, errors.Message(err)), "relations", strconv.Itoa(index), "condition")
+			}
+			if !isBoolean(expr) {
+				return nil, invalidRuleSet(locator, fmt.Errorf("relation from %v to %v has a condition of type %v, it should be bool", relation.From, relation.To, expr.Type()), "relations", strconv.Itoa(index), "condition")
+			}
+			edge.Condition = expr
+		}
+		fromNode.Relations = append(fromNode.Relations, edge)
+		toNode.Incoming = append(toNode.Incoming, edge)
 
 		indegree[toNode] = indegree[toNode] + 1
 	}
 
-	for key, val := range indegree {
-		if val == 0 {
+	if err := validateDAG(nodes); err != nil {
+		return nil, err
+	}
+
+	for _, node := range nodes {
+		if err := validateJoin(node); err != nil {
+			return nil, invalidRuleSet(locator, err, "rules", node.Rule.ID, "join")
+		}
+	}
+
+	executionOrder := topologicalOrder(nodes, indegree)
+
+	for _, node := range executionOrder {
+		if indegree[node] == 0 {
 			rootNode.Relations = append(rootNode.Relations, &Edge{
-				Destination: key,
+				Source:      rootNode,
+				Destination: node,
 			})
 		}
 	}
 
 	return &RuleGraph{
-		ID:   data.ID,
-		Root: rootNode,
+		ID:             data.ID,
+		Root:           rootNode,
+		ExecutionOrder: executionOrder,
+		Constants:      constantValues(constants),
+		Schema:         schema,
 	}, nil
 }
 
-func resolvePredicate(predicates map[string]string, expression string) (string, error) {
+// variableTypes returns the types of the variables used to type check the
+// expressions, the types declared in the schema override the configured ones
+func (p *parser) variableTypes(schema []*Variable) map[string]models.DataType {
+	types := make(map[string]models.DataType, len(p.config.variableTypes)+len(schema))
+	for name, dataType := range p.config.variableTypes {
+		types[name] = dataType
+	}
+	for _, variable := range schema {
+		types[variable.Name] = variable.Type
+	}
+	return types
+}
 
-	isPredicate := func(s string) bool {
-		pattern := "Predicate:"
+// isBoolean tells if an expression can be used as a predicate, the expressions
+// of unknown type are checked on evaluation
+func isBoolean(expr expressions.Expression) bool {
+	return expr.Type() == models.DataTypeBool || expr.Type() == models.DataTypeUnknown
+}
 
-		if len(s) <= len(pattern) {
-			return false
+// parseConstants compiles the constants of the rule-set, a constant
+// can be a number, a string, a bool or a list of them
+func parseConstants(locator Locator, defs map[string]interface{}) (map[string]expressions.Expression, error) {
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	constants := make(map[string]expressions.Expression, len(defs))
+	for _, name := range names {
+		expr, err := expressions.NewConstant(defs[name])
+		if err != nil {
+			return nil, invalidRuleSet(locator, fmt.Errorf("constant %v has invalid value, %v", name, errors.Message(err)), "constants", name)
 		}
+		constants[name] = expr
+	}
+	return constants, nil
+}
 
-		for index := range pattern {
-			if pattern[index] != s[index] {
-				return false
-			}
+func constantValues(constants map[string]expressions.Expression) map[string]interface{} {
+	values := make(map[string]interface{}, len(constants))
+	for name, expr := range constants {
+		res, err := expr.Evaluate(&expressions.EvaluationRequest{})
+		if err != nil {
+			continue
+		}
+		values[name] = res.Value.Interface()
+	}
+	return values
+}
+
+// decode decodes the rule-set document using the decoder registered
+// for its format
+func (p *parser) decode(data []byte) (*ruleSetDefinition, Locator, error) {
+	format := p.config.format
+	if format == "" {
+		format = sniffFormat(data)
+	}
+	decoder, ok := p.config.decoders[format]
+	if !ok {
+		return nil, nil, errors.New(ErrInvalidRuleSet, fmt.Errorf("no decoder registered for format %v", format))
+	}
+
+	definition := &ruleSetDefinition{}
+	locator, err := decoder.Decode(data, definition)
+	if err != nil {
+		return nil, nil, errors.New(ErrInvalidRuleSet, fmt.Errorf("failed to decode %v rule-set, %v", format, err.Error()))
+	}
+	return definition, locator, nil
+}
+
+// parseTimestamp parses an RFC3339 timestamp, an empty string is
+// parsed as the zero time
+func parseTimestamp(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// validateJoin validates the join mode of a rule against the number
+// of relations coming to it, an empty join mode is set to JoinAny and
+// a join_count is only allowed with JoinNOfM
+func validateJoin(node *Node) error {
+	switch node.Rule.Join {
+	case "":
+		node.Rule.Join = JoinAny
+	case JoinAny:
+	case JoinAll:
+		if sources := sourceCount(node); sources < len(node.Incoming) {
+			return fmt.Errorf("rule %v has join all with relations on both true and false from the same rule, it can never be evaluated",
+				node.Rule.ID)
+		}
+	case JoinNOfM:
+		if node.Rule.JoinCount < 1 || node.Rule.JoinCount > len(node.Incoming) {
+			return fmt.Errorf("rule %v has join_count %v, it should be between 1 and %v, the number of relations to the rule",
+				node.Rule.ID, node.Rule.JoinCount, len(node.Incoming))
+		}
+		if sources := sourceCount(node); node.Rule.JoinCount > sources {
+			return fmt.Errorf("rule %v has join_count %v, but only %v of its relations can be followed together, "+
+				"the relations on true and false from the same rule exclude each other", node.Rule.ID, node.Rule.JoinCount, sources)
 		}
-		return true
+	default:
+		return fmt.Errorf("rule %v has invalid join %v", node.Rule.ID, node.Rule.Join)
+	}
+	if node.Rule.Join != JoinNOfM && node.Rule.JoinCount != 0 {
+		return fmt.Errorf("rule %v has join_count %v with join %v, join_count is only used with join %v",
+			node.Rule.ID, node.Rule.JoinCount, node.Rule.Join, JoinNOfM)
+	}
+	return nil
+}
+
+// sourceCount returns the number of rules with a relation to the node, it
+// is the most relations to the node that can be followed in a run as the
+// relations on true and false from a rule exclude each other
+func sourceCount(node *Node) int {
+	sources := make(map[*Node]bool, len(node.Incoming))
+	for _, edge := range node.Incoming {
+		sources[edge.Source] = true
 	}
+	return len(sources)
+}
+
+// parsePostEvals compiles the post-evals defined under the given field
+// of a rule i.e post_evals or on_false
+func parsePostEvals(compiler *compiler, locator Locator, ruleID string, field string, definitions []postEvalDefinition) ([]*RulePostEval, error) {
+	postEvals := make([]*RulePostEval, 0, len(definitions))
+	for index, postEval := range definitions {
+		postEvalPath := []string{"rules", ruleID, field, strconv.Itoa(index)}
 
-	tokens := strings.Split(expression, " ")
-	for index := range tokens {
-		if isPredicate(tokens[index]) {
-			predicateID := strings.Split(tokens[index], ":")[1]
-			if _, ok := predicates[predicateID]; !ok {
-				return "", fmt.Errorf("reference to invalid predicate %v", predicateID)
+		output := &RulePostEval{
+			ID:   postEval.ID,
+			Type: postEval.Type,
+			Echo: postEval.Echo,
+		}
+
+		switch postEval.Type {
+		case OutputTypeExpression:
+			expr, err := compiler.compile(postEval.Value)
+			if err != nil {
+				return nil, invalidRuleSet(locator, fmt.Errorf("rule %v has invalid predicate for output %v, %v", ruleID, postEval.ID, errors.Message(err)), postEvalPath...)
 			}
-			tokens[index] = predicates[predicateID]
+			output.Evaluable = expr
+
+		case OutputTypeConstant:
+			output.Const = postEval.Value
+
+		default:
+			return nil, invalidRuleSet(locator, fmt.Errorf("invalid output type used for output %v in rule %v", postEval.ID, ruleID), postEvalPath...)
 		}
+		postEvals = append(postEvals, output)
 	}
+	return postEvals, nil
+}
 
-	return strings.Join(tokens, " "), nil
+// invalidRuleSet returns an ErrInvalidRuleSet error, the position of the
+// element at the given path is added to the message if the locator knows it
+func invalidRuleSet(locator Locator, err error, path ...string) error {
+	if locator != nil {
+		if position := locator.Locate(path...); position != "" {
+			err = fmt.Errorf("%v at %v", err.Error(), position)
+		}
+	}
+	return errors.New(ErrInvalidRuleSet, err)
 }
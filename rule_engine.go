@@ -2,32 +2,64 @@ package coffeemachine
 
 import (
 	"io"
+	"sync/atomic"
 )
 
 // RuleEngine is an interface that exposes a Run method to evaluate a RuleSet against
 // provided parameters
+// RunStream can be used to process the outputs as the rules are evaluated
+// and Reload replaces the rule-set the engine runs with
 type RuleEngine interface {
 	Run(req *RuleEngineRequest) (*RuleEngineResponse, error)
+	RunStream(req *RuleEngineRequest, handler OutputHandler) error
+	Reload(ruleSet io.Reader) error
 }
 
 type ruleengine struct {
-	evaluator Evaluator
+	options []Option
+	// loaded is the version of the rule-set the engine was created with,
+	// if it was created from a Loader
+	loaded string
+	// evaluator holds the Evaluator for the current rule-graph, it is
+	// swapped atomically on a reload
+	evaluator atomic.Value
 }
 
 // NewRuleEngine is a constructor for RuleEngine
 // It accpets an io.Reader to read the rule-set
 // and returns an instance of engine which can be run with
 // different set of parameter values
-func NewRuleEngine(ruleSet io.Reader) (RuleEngine, error) {
-	ruleGraph, err := NewParser().Parse(ruleSet)
-	if err != nil {
+// The options are applied to the parser and the evaluator, including on reloads
+func NewRuleEngine(ruleSet io.Reader, options ...Option) (RuleEngine, error) {
+	engine := &ruleengine{
+		options: options,
+	}
+	if err := engine.Reload(ruleSet); err != nil {
 		return nil, err
 	}
+	return engine, nil
+}
 
-	return &ruleengine{
-		evaluator: NewEvaluator(ruleGraph),
-	}, nil
-
+// NewRuleEngineFromLoader is a constructor for RuleEngine
+// It loads the current version of the rule-set from the loader, a Watcher
+// can be used to keep the engine in sync with the loader
+// The version is read before the rule-set, so a change made while loading
+// is picked up by the Watcher
+func NewRuleEngineFromLoader(loader Loader, options ...Option) (RuleEngine, error) {
+	version, err := loader.Version()
+	if err != nil {
+		return nil, err
+	}
+	ruleSet, err := loader.Load()
+	if err != nil {
+		return nil, err
+	}
+	engine, err := NewRuleEngine(ruleSet, options...)
+	if err != nil {
+		return nil, err
+	}
+	engine.(*ruleengine).loaded = version
+	return engine, nil
 }
 
 // Run method accepts a request containing parameter value and output
@@ -35,5 +67,32 @@ func NewRuleEngine(ruleSet io.Reader) (RuleEngine, error) {
 // It evaluates the rule-set against provided values and returns the response
 // as per the output criteria
 func (r *ruleengine) Run(req *RuleEngineRequest) (*RuleEngineResponse, error) {
-	return r.evaluator.Evaluate(req)
+	return r.getEvaluator().Evaluate(req)
+}
+
+// RunStream method evaluates the rule-set against provided values and calls
+// the handler with the output of every rule that evaluates to true, and with
+// the on_false output of every rule that evaluates to false, the latter has
+// OnFalse set
+// The outputs are filtered by the strategy of the request as in Run
+// The evaluation stops as soon as the handler returns an error, the error is
+// returned as it is
+func (r *ruleengine) RunStream(req *RuleEngineRequest, handler OutputHandler) error {
+	return r.getEvaluator().EvaluateStream(req, handler)
+}
+
+// Reload method parses the rule-set and swaps the rule-graph used by the engine
+// The engine keeps running with the previous rule-graph if the rule-set is invalid
+// Runs that are already in progress finish with the rule-graph they started with
+func (r *ruleengine) Reload(ruleSet io.Reader) error {
+	ruleGraph, err := NewParser(r.options...).Parse(ruleSet)
+	if err != nil {
+		return err
+	}
+	r.evaluator.Store(NewEvaluator(ruleGraph, r.options...))
+	return nil
+}
+
+func (r *ruleengine) getEvaluator() Evaluator {
+	return r.evaluator.Load().(Evaluator)
 }
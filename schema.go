@@ -0,0 +1,121 @@
+package coffeemachine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anshal21/coffee-machine/lib/errors"
+	"github.com/anshal21/coffee-machine/lib/models"
+)
+
+// parseSchema parses the variables declared in the schema of the rule-set
+// the default of a variable should be of the type of the variable
+func parseSchema(locator Locator, defs map[string]*variableDefinition) ([]*Variable, error) {
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	schema := make([]*Variable, 0, len(defs))
+	for _, name := range names {
+		def := defs[name]
+		if def == nil {
+			return nil, invalidRuleSet(locator, fmt.Errorf("variable %v has no type", name), "schema", name)
+		}
+		dataType, ok := parseDataType(def.Type)
+		if !ok {
+			return nil, invalidRuleSet(locator, fmt.Errorf("variable %v has invalid type %v", name, def.Type), "schema", name, "type")
+		}
+		if def.Default != nil {
+			if def.Required {
+				return nil, invalidRuleSet(locator, fmt.Errorf("variable %v is required, it can't have a default", name), "schema", name, "default")
+			}
+			if defaultType := dataTypeOf(def.Default); defaultType != dataType {
+				return nil, invalidRuleSet(locator, fmt.Errorf("variable %v has default of type %v, it should be %v", name, defaultType, dataType), "schema", name, "default")
+			}
+		}
+		schema = append(schema, &Variable{
+			Name:     name,
+			Type:     dataType,
+			Required: def.Required,
+			Default:  def.Default,
+		})
+	}
+	return schema, nil
+}
+
+func parseDataType(name string) (models.DataType, bool) {
+	for _, dataType := range []models.DataType{models.DataTypeNumber, models.DataTypeString, models.DataTypeBool, models.DataTypeList} {
+		if dataType.String() == name {
+			return dataType, true
+		}
+	}
+	return models.DataTypeUnknown, false
+}
+
+// dataTypeOf returns the type of a variable value, as understood by the
+// expressions
+func dataTypeOf(value interface{}) models.DataType {
+	switch value.(type) {
+	case float64, int:
+		return models.DataTypeNumber
+	case string:
+		return models.DataTypeString
+	case bool:
+		return models.DataTypeBool
+	case []interface{}, []string, []float64, []int:
+		return models.DataTypeList
+	default:
+		return models.DataTypeUnknown
+	}
+}
+
+// validateVariables checks the variables against the schema and reports
+// all the problems at once, the defaults of the missing variables are
+// applied to a copy of the variables
+func validateVariables(schema []*Variable, variables map[string]interface{}) (map[string]interface{}, error) {
+	validated, copied := variables, false
+	details := make([]errors.Detail, 0)
+	for _, variable := range schema {
+		value, ok := variables[variable.Name]
+		if !ok {
+			if variable.Required {
+				details = append(details, errors.Detail{
+					Field: variable.Name,
+					Msg:   fmt.Sprintf("variable %v is required", variable.Name),
+				})
+				continue
+			}
+			if variable.Default == nil {
+				continue
+			}
+			if !copied {
+				validated = make(map[string]interface{}, len(variables)+len(schema))
+				for name, value := range variables {
+					validated[name] = value
+				}
+				copied = true
+			}
+			validated[variable.Name] = variable.Default
+			continue
+		}
+		if dataType := dataTypeOf(value); dataType != variable.Type {
+			details = append(details, errors.Detail{
+				Field: variable.Name,
+				Msg:   fmt.Sprintf("variable %v should be of type %v, found %v", variable.Name, variable.Type, dataType),
+			})
+		}
+	}
+
+	if len(details) > 0 {
+		messages := make([]string, 0, len(details))
+		for _, detail := range details {
+			messages = append(messages, detail.Msg)
+		}
+		return nil, errors.New(ErrInvalidVariables,
+			fmt.Errorf("variables don't conform to the schema, %v", strings.Join(messages, ", "))).WithDetails(details...)
+	}
+	return validated, nil
+}
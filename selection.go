@@ -0,0 +1,93 @@
+package coffeemachine
+
+// ruleSelection is the set of rules a request runs, built from the
+// include and exclude filters of the request
+// selected holds the rules that pass the filters, their outputs are
+// returned, and required holds the selected rules along with all their
+// ancestors, which are evaluated to keep the semantics of the relations
+type ruleSelection struct {
+	selected map[*Node]struct{}
+	required map[*Node]struct{}
+}
+
+// newRuleSelection returns the selection for the request, it returns
+// nil if the request has no filters i.e all the rules are selected
+func newRuleSelection(req *RuleEngineRequest, ruleGraph *RuleGraph) *ruleSelection {
+	if len(req.IncludeRules) == 0 && len(req.IncludeTags) == 0 &&
+		len(req.ExcludeRules) == 0 && len(req.ExcludeTags) == 0 {
+		return nil
+	}
+
+	includeRules := toSet(req.IncludeRules)
+	includeTags := toSet(req.IncludeTags)
+	excludeRules := toSet(req.ExcludeRules)
+	excludeTags := toSet(req.ExcludeTags)
+	includeAll := len(includeRules) == 0 && len(includeTags) == 0
+
+	selection := &ruleSelection{
+		selected: make(map[*Node]struct{}),
+		required: make(map[*Node]struct{}),
+	}
+
+	for _, node := range ruleGraph.ExecutionOrder {
+		_, included := includeRules[node.Rule.ID]
+		included = included || includeAll || hasAnyTag(node.Rule, includeTags)
+		_, excluded := excludeRules[node.Rule.ID]
+		excluded = excluded || hasAnyTag(node.Rule, excludeTags)
+		if included && !excluded {
+			selection.selected[node] = struct{}{}
+		}
+	}
+
+	// the ancestors of a node appear before it in the execution order, so
+	// walking the order backwards marks all of them
+	for index := len(ruleGraph.ExecutionOrder) - 1; index >= 0; index-- {
+		node := ruleGraph.ExecutionOrder[index]
+		_, selected := selection.selected[node]
+		_, required := selection.required[node]
+		if !selected && !required {
+			continue
+		}
+		selection.required[node] = struct{}{}
+		for _, edge := range node.Incoming {
+			selection.required[edge.Source] = struct{}{}
+		}
+	}
+
+	return selection
+}
+
+// isRequired tells if the node has to be evaluated
+func (r *ruleSelection) isRequired(node *Node) bool {
+	if r == nil {
+		return true
+	}
+	_, ok := r.required[node]
+	return ok
+}
+
+// isSelected tells if the output of the node has to be returned
+func (r *ruleSelection) isSelected(node *Node) bool {
+	if r == nil {
+		return true
+	}
+	_, ok := r.selected[node]
+	return ok
+}
+
+func hasAnyTag(rule *Rule, tags map[string]struct{}) bool {
+	for _, tag := range rule.Tags {
+		if _, ok := tags[tag]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		set[value] = struct{}{}
+	}
+	return set
+}
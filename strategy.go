@@ -0,0 +1,136 @@
+package coffeemachine
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/anshal21/coffee-machine/lib/errors"
+)
+
+// Strategy is a type to represent the conflict-resolution strategy used
+// to pick the outputs of a run, when more than one rule evaluates to true
+type Strategy int
+
+// Set of supported conflict-resolution strategies
+const (
+	// StrategyAllMatches returns the outputs of all the rules that evaluate
+	// to true, along with the on_false outputs of the rules that evaluate to
+	// false, in the order of execution
+	StrategyAllMatches Strategy = iota
+	// StrategyFirstMatch returns only the output of the rule with the highest
+	// priority, ties are resolved by the order of execution
+	// The on_false outputs are left out by all the strategies but
+	// StrategyAllMatches
+	StrategyFirstMatch
+	// StrategyHighestPriority returns the outputs of all the rules that share
+	// the highest priority, in the order of execution
+	StrategyHighestPriority
+	// StrategyTopN returns the outputs of the N rules with the highest priority
+	// where N is the Limit of the request, the outputs are ordered by priority
+	// and then by the order of execution
+	StrategyTopN
+)
+
+func (s Strategy) String() string {
+	switch s {
+	case StrategyAllMatches:
+		return "all_matches"
+	case StrategyFirstMatch:
+		return "first_match"
+	case StrategyHighestPriority:
+		return "highest_priority"
+	case StrategyTopN:
+		return "top_n"
+	default:
+		return "unknown"
+	}
+}
+
+// outputSelector applies the conflict-resolution strategy of a request
+// on the outputs of a run
+// All the outputs are passed on to the handler as they come for
+// StrategyAllMatches, for the other strategies the outputs are held back
+// till the run finishes
+type outputSelector struct {
+	strategy   Strategy
+	limit      int
+	handler    OutputHandler
+	candidates []*candidate
+}
+
+type candidate struct {
+	priority   int
+	ruleOutput *RuleOutput
+}
+
+func newOutputSelector(req *RuleEngineRequest, handler OutputHandler) (*outputSelector, error) {
+	switch req.Strategy {
+	case StrategyAllMatches, StrategyFirstMatch, StrategyHighestPriority:
+	case StrategyTopN:
+		if req.Limit <= 0 {
+			return nil, errors.New(ErrInvalidRequest, fmt.Errorf("strategy %v requires a positive limit, got %v", req.Strategy, req.Limit))
+		}
+	default:
+		return nil, errors.New(ErrInvalidRequest, fmt.Errorf("unsupported strategy %v", int(req.Strategy)))
+	}
+
+	return &outputSelector{
+		strategy: req.Strategy,
+		limit:    req.Limit,
+		handler:  handler,
+	}, nil
+}
+
+// add adds the output of a rule to the candidates, the on_false outputs
+// of the rules that evaluated to false are returned only by
+// StrategyAllMatches as they don't belong to a matched rule
+func (o *outputSelector) add(node *Node, ruleOutput *RuleOutput) error {
+	if o.strategy == StrategyAllMatches {
+		return o.handler(ruleOutput)
+	}
+	if ruleOutput.OnFalse {
+		return nil
+	}
+	o.candidates = append(o.candidates, &candidate{
+		priority:   node.Rule.Priority,
+		ruleOutput: ruleOutput,
+	})
+	return nil
+}
+
+// flush hands over the selected outputs to the handler, it is called
+// once all the rules have been evaluated
+func (o *outputSelector) flush() error {
+	if len(o.candidates) == 0 {
+		return nil
+	}
+
+	// the candidates are in the order of execution, a stable sort keeps
+	// it for the candidates with the same priority
+	sort.SliceStable(o.candidates, func(i, j int) bool {
+		return o.candidates[i].priority > o.candidates[j].priority
+	})
+
+	selected := o.candidates
+	switch o.strategy {
+	case StrategyFirstMatch:
+		selected = selected[:1]
+	case StrategyHighestPriority:
+		count := 1
+		for count < len(selected) && selected[count].priority == selected[0].priority {
+			count++
+		}
+		selected = selected[:count]
+	case StrategyTopN:
+		if len(selected) > o.limit {
+			selected = selected[:o.limit]
+		}
+	}
+
+	for _, candidate := range selected {
+		if err := o.handler(candidate.ruleOutput); err != nil {
+			return err
+		}
+	}
+	return nil
+}
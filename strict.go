@@ -0,0 +1,391 @@
+package coffeemachine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NewStrictJSONDecoder is a constructor for a Decoder of JSON rule-sets that
+// rejects unknown fields, duplicate keys and values of a wrong type, every
+// problem is reported with its JSON path and byte offset in the document
+func NewStrictJSONDecoder() Decoder {
+	return &strictJSONDecoder{}
+}
+
+type strictJSONDecoder struct{}
+
+func (s *strictJSONDecoder) Decode(data []byte, v interface{}) (Locator, error) {
+	checker := &jsonChecker{
+		data:    data,
+		decoder: json.NewDecoder(bytes.NewReader(data)),
+	}
+	checker.decoder.UseNumber()
+	if err := checker.check(reflect.TypeOf(v), "$"); err != nil {
+		return nil, err
+	}
+	if len(checker.problems) > 0 {
+		return nil, fmt.Errorf("%v", strings.Join(checker.problems, ", "))
+	}
+	return NewJSONDecoder().Decode(data, v)
+}
+
+// NewStrictYAMLDecoder is a constructor for a Decoder of YAML rule-sets that
+// rejects unknown fields, duplicate keys and values of a wrong type, every
+// problem is reported with its path, along with the line and column in the
+// document, a number or a bool used as a string has to be quoted
+func NewStrictYAMLDecoder() Decoder {
+	return &strictYAMLDecoder{}
+}
+
+type strictYAMLDecoder struct{}
+
+func (s *strictYAMLDecoder) Decode(data []byte, v interface{}) (Locator, error) {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(data, root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, errEmptyRuleSet
+	}
+	checker := &yamlChecker{}
+	checker.check(root.Content[0], reflect.TypeOf(v), "$")
+	if len(checker.problems) > 0 {
+		return nil, fmt.Errorf("%v", strings.Join(checker.problems, ", "))
+	}
+	return NewYAMLDecoder().Decode(data, v)
+}
+
+// valueKind is the kind of value expected for a go type in a document
+type valueKind string
+
+const (
+	kindObject valueKind = "object"
+	kindArray  valueKind = "array"
+	kindString valueKind = "string"
+	kindNumber valueKind = "number"
+	kindBool   valueKind = "bool"
+	kindAny    valueKind = "any"
+)
+
+var (
+	_ruleDefinitionsType = reflect.TypeOf(ruleDefinitions{})
+	// _ruleDefinitionsSchema is the shape of the rules in a document, the
+	// rules are decoded as an object keyed by the rule id
+	_ruleDefinitionsSchema = reflect.TypeOf(map[string]*ruleDefinition{})
+)
+
+// schemaOf returns the type that describes the shape of a value of type t
+// in a rule-set document
+func schemaOf(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == _ruleDefinitionsType {
+		return _ruleDefinitionsSchema
+	}
+	return t
+}
+
+func kindOf(t reflect.Type) valueKind {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return kindObject
+	case reflect.Slice, reflect.Array:
+		return kindArray
+	case reflect.String:
+		return kindString
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return kindNumber
+	case reflect.Bool:
+		return kindBool
+	default:
+		return kindAny
+	}
+}
+
+// fieldType returns the type of the field of a struct with the given
+// name in the document, the names are matched exactly
+func fieldType(t reflect.Type, name string) (reflect.Type, bool) {
+	for index := 0; index < t.NumField(); index++ {
+		field := t.Field(index)
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if tag == name && tag != "-" {
+			return field.Type, true
+		}
+	}
+	return nil, false
+}
+
+func isInteger(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return false
+	}
+	return true
+}
+
+// jsonChecker walks the tokens of a JSON document along with the type the
+// document is decoded into and collects the problems found on the way
+type jsonChecker struct {
+	data     []byte
+	decoder  *json.Decoder
+	problems []string
+}
+
+func (j *jsonChecker) report(offset int64, path, format string, args ...interface{}) {
+	j.problems = append(j.problems, fmt.Sprintf("%v at %v, offset %v", fmt.Sprintf(format, args...), path, offset))
+}
+
+// offset returns the byte offset where the next token starts
+func (j *jsonChecker) offset() int64 {
+	offset := j.decoder.InputOffset()
+	for offset < int64(len(j.data)) {
+		switch j.data[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+			continue
+		}
+		break
+	}
+	return offset
+}
+
+// check reads the next value of the document and checks it against t, a
+// value of a wrong type is skipped
+func (j *jsonChecker) check(t reflect.Type, path string) error {
+	offset := j.offset()
+	token, err := j.decoder.Token()
+	if err != nil {
+		if err == io.EOF {
+			return fmt.Errorf("unexpected end of the document at %v, offset %v", path, offset)
+		}
+		return fmt.Errorf("%v at %v, offset %v", err.Error(), path, offset)
+	}
+	if token == nil {
+		return nil
+	}
+
+	t = schemaOf(t)
+	expected := kindOf(t)
+	found := jsonKind(token)
+	if expected != kindAny && expected != found {
+		j.report(offset, path, "expected %v but found %v", expected, found)
+		return j.skip(token)
+	}
+
+	switch found {
+	case kindObject:
+		return j.checkObject(t, path)
+	case kindArray:
+		return j.checkArray(t, path)
+	case kindNumber:
+		if expected == kindNumber && isInteger(t) {
+			if _, err := token.(json.Number).Int64(); err != nil {
+				j.report(offset, path, "expected an integer but found %v", token)
+			}
+		}
+	}
+	return nil
+}
+
+func (j *jsonChecker) checkObject(t reflect.Type, path string) error {
+	seen := make(map[string]bool)
+	for j.decoder.More() {
+		offset := j.offset()
+		token, err := j.decoder.Token()
+		if err != nil {
+			return fmt.Errorf("%v at %v, offset %v", err.Error(), path, offset)
+		}
+		key := token.(string)
+		keyPath := path + "." + key
+		if seen[key] {
+			j.report(offset, keyPath, "duplicate key %v", key)
+		}
+		seen[key] = true
+
+		var valueType reflect.Type
+		switch t.Kind() {
+		case reflect.Struct:
+			fieldType, ok := fieldType(t, key)
+			if !ok {
+				j.report(offset, keyPath, "unknown field %v", key)
+				if err := j.skipValue(); err != nil {
+					return err
+				}
+				continue
+			}
+			valueType = fieldType
+		case reflect.Map:
+			valueType = t.Elem()
+		default:
+			valueType = t
+		}
+		if err := j.check(valueType, keyPath); err != nil {
+			return err
+		}
+	}
+	_, err := j.decoder.Token()
+	return err
+}
+
+func (j *jsonChecker) checkArray(t reflect.Type, path string) error {
+	elemType := t
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		elemType = t.Elem()
+	}
+	for index := 0; j.decoder.More(); index++ {
+		if err := j.check(elemType, fmt.Sprintf("%v[%v]", path, index)); err != nil {
+			return err
+		}
+	}
+	_, err := j.decoder.Token()
+	return err
+}
+
+// skipValue skips the next value of the document
+func (j *jsonChecker) skipValue() error {
+	token, err := j.decoder.Token()
+	if err != nil {
+		return err
+	}
+	return j.skip(token)
+}
+
+// skip skips the rest of a value starting with the token
+func (j *jsonChecker) skip(token json.Token) error {
+	delim, ok := token.(json.Delim)
+	if !ok || (delim != '{' && delim != '[') {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		token, err := j.decoder.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := token.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+		}
+	}
+	return nil
+}
+
+func jsonKind(token json.Token) valueKind {
+	switch token.(type) {
+	case json.Delim:
+		if token.(json.Delim) == '{' {
+			return kindObject
+		}
+		return kindArray
+	case string:
+		return kindString
+	case json.Number:
+		return kindNumber
+	case bool:
+		return kindBool
+	default:
+		return kindAny
+	}
+}
+
+// yamlChecker walks the nodes of a YAML document along with the type the
+// document is decoded into and collects the problems found on the way
+type yamlChecker struct {
+	problems []string
+}
+
+func (y *yamlChecker) report(node *yaml.Node, path, format string, args ...interface{}) {
+	y.problems = append(y.problems, fmt.Sprintf("%v at %v, line %v, column %v",
+		fmt.Sprintf(format, args...), path, node.Line, node.Column))
+}
+
+func (y *yamlChecker) check(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null" {
+		return
+	}
+
+	t = schemaOf(t)
+	expected := kindOf(t)
+	found := yamlKind(node)
+	// a number or a bool has to be quoted to be used as a string, a
+	// timestamp is kept as it's written
+	if expected != kindAny && expected != found && (expected != kindString || found != kindAny) {
+		y.report(node, path, "expected %v but found %v", expected, found)
+		return
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		seen := make(map[string]bool)
+		for index := 0; index+1 < len(node.Content); index += 2 {
+			keyNode, valueNode := node.Content[index], node.Content[index+1]
+			key := keyNode.Value
+			keyPath := path + "." + key
+			if seen[key] {
+				y.report(keyNode, keyPath, "duplicate key %v", key)
+			}
+			seen[key] = true
+
+			valueType := t
+			switch t.Kind() {
+			case reflect.Struct:
+				fieldType, ok := fieldType(t, key)
+				if !ok {
+					y.report(keyNode, keyPath, "unknown field %v", key)
+					continue
+				}
+				valueType = fieldType
+			case reflect.Map:
+				valueType = t.Elem()
+			}
+			y.check(valueNode, valueType, keyPath)
+		}
+	case yaml.SequenceNode:
+		elemType := t
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			elemType = t.Elem()
+		}
+		for index, elem := range node.Content {
+			y.check(elem, elemType, path+"["+strconv.Itoa(index)+"]")
+		}
+	case yaml.ScalarNode:
+		if expected == kindNumber && isInteger(t) && node.ShortTag() != "!!int" {
+			y.report(node, path, "expected an integer but found %v", node.Value)
+		}
+	}
+}
+
+func yamlKind(node *yaml.Node) valueKind {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return kindObject
+	case yaml.SequenceNode:
+		return kindArray
+	}
+	switch node.ShortTag() {
+	case "!!int", "!!float":
+		return kindNumber
+	case "!!bool":
+		return kindBool
+	case "!!str":
+		return kindString
+	default:
+		return kindAny
+	}
+}
@@ -0,0 +1,36 @@
+package tests
+
+var _conditionalRuleSet = `{
+  "id": "conditional_ruleset",
+  "rules": {
+    "ROUTER": {
+      "predicate": "amount > 0",
+      "post_evals": [
+        {
+          "id": "score",
+          "type": "EXPR",
+          "value": "amount * 2"
+        }
+      ]
+    },
+    "HIGH": {
+      "predicate": "ROUTER.score > 0"
+    },
+    "LOW": {
+      "predicate": "amount > 0"
+    }
+  },
+  "relations": [
+    {
+      "from": "ROUTER",
+      "to": "HIGH",
+      "forward_output": true,
+      "condition": "ROUTER.score > 1000"
+    },
+    {
+      "from": "ROUTER",
+      "to": "LOW",
+      "condition": "amount <= 500"
+    }
+  ]
+}`
@@ -0,0 +1,39 @@
+package tests
+
+var _constantsRuleSet = `{
+  "id": "constants_ruleset",
+  "constants": {
+    "LIMIT": 1000,
+    "COUNTRIES": ["IN", "US"],
+    "CURRENCY": "INR",
+    "STRICT": true
+  },
+  "predicates": {
+    "HIGH_VALUE": "amount > Constant:LIMIT"
+  },
+  "rules": {
+    "R1": {
+      "predicate": "Predicate:HIGH_VALUE && country in Constant:COUNTRIES",
+      "post_evals": [
+        {
+          "id": "limit",
+          "type": "EXPR",
+          "value": "Constant:LIMIT * 2"
+        }
+      ]
+    },
+    "R2": {
+      "predicate": "currency == Constant:CURRENCY && Constant:STRICT"
+    }
+  }
+}`
+
+var _constantsYAMLRuleSet = `
+id: constants_ruleset
+constants:
+  LIMIT: 1000
+  COUNTRIES: [IN, US]
+rules:
+  R1:
+    predicate: amount > Constant:LIMIT && country in Constant:COUNTRIES
+`
@@ -0,0 +1,56 @@
+package tests
+
+var _decisionTreeRuleSet = `{
+  "id": "decision_tree_ruleset",
+  "rules": {
+    "R1": {
+      "predicate": "amount > 1000",
+      "post_evals": [
+        {
+          "id": "tier",
+          "type": "CONST",
+          "value": "premium"
+        }
+      ],
+      "on_false": [
+        {
+          "id": "tier",
+          "type": "CONST",
+          "value": "standard"
+        }
+      ]
+    },
+    "R2": {
+      "predicate": "amount > 100",
+      "post_evals": [
+        {
+          "id": "discount",
+          "type": "EXPR",
+          "value": "amount / 10"
+        }
+      ]
+    },
+    "R3": {
+      "predicate": "R1.tier == \"premium\"",
+      "post_evals": [
+        {
+          "id": "discount",
+          "type": "EXPR",
+          "value": "amount / 5"
+        }
+      ]
+    }
+  },
+  "relations": [
+    {
+      "from": "R1",
+      "to": "R2",
+      "when": false
+    },
+    {
+      "from": "R1",
+      "to": "R3",
+      "forward_output": true
+    }
+  ]
+}`
@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	coffeemachine "github.com/anshal21/coffee-machine"
+	"github.com/anshal21/coffee-machine/lib/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_YAMLRuleSet(t *testing.T) {
+	request := &coffeemachine.RuleEngineRequest{
+		Variables: map[string]interface{}{
+			"a": 10,
+			"b": 8,
+			"c": 6,
+		},
+	}
+
+	jsonEngine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_simpleDependencyRuleSet)))
+	assert.NoError(t, err)
+	expectedRes, err := jsonEngine.Run(request)
+	assert.NoError(t, err)
+
+	t.Run("yaml | sniffed format", func(t *testing.T) {
+		engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_simpleDependencyYAMLRuleSet)))
+		assert.NoError(t, err)
+		res, err := engine.Run(request)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedRes, res)
+	})
+
+	t.Run("yaml | explicit format", func(t *testing.T) {
+		engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_simpleDependencyYAMLRuleSet)),
+			coffeemachine.WithFormat(coffeemachine.FormatYAML))
+		assert.NoError(t, err)
+		res, err := engine.Run(request)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedRes, res)
+	})
+
+	t.Run("yaml | error reports the position of the rule", func(t *testing.T) {
+		_, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_invalidYAMLRuleSet)))
+		assert.Error(t, err)
+		assert.Equal(t, coffeemachine.ErrInvalidRuleSet, err.(*errors.Error).Code)
+		assert.Contains(t, err.(*errors.Error).Msg, "rule R2 has invalid predicate")
+		assert.Contains(t, err.(*errors.Error).Msg, "at line 9, column 5")
+	})
+}
+
+type countingDecoder struct {
+	calls int
+}
+
+func (c *countingDecoder) Decode(data []byte, v interface{}) (coffeemachine.Locator, error) {
+	c.calls++
+	return coffeemachine.NewJSONDecoder().Decode(data, v)
+}
+
+func Test_CustomDecoder(t *testing.T) {
+	ruleSet := []byte(_simpleRuleSet)
+
+	_, err := coffeemachine.NewRuleEngine(bytes.NewReader(ruleSet), coffeemachine.WithFormat("custom"))
+	assert.Error(t, err)
+
+	decoder := &countingDecoder{}
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader(ruleSet),
+		coffeemachine.WithFormat("custom"),
+		coffeemachine.WithDecoder("custom", decoder))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, decoder.calls)
+
+	res, err := engine.Run(&coffeemachine.RuleEngineRequest{
+		Variables: map[string]interface{}{
+			"a": 10,
+			"b": 8,
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, res.Outputs, 1)
+}
+
+func Test_StrictDecoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		ruleSet string
+		errMsg  string
+	}{
+		{
+			name:    "strict decoding | json",
+			ruleSet: _misconfiguredRuleSet,
+			errMsg: "failed to decode json rule-set, duplicate key P1 at $.predicates.P1, offset 76, " +
+				"unknown field post_eval at $.rules.R1.post_eval, offset 161, " +
+				"expected number but found string at $.rules.R1.order, offset 305, " +
+				"duplicate key R1 at $.rules.R1, offset 324, " +
+				"expected an integer but found 1.5 at $.rules.R1.priority, offset 385, " +
+				"expected bool but found string at $.relations[0].when, offset 475",
+		},
+		{
+			name:    "strict decoding | yaml",
+			ruleSet: _misconfiguredYAMLRuleSet,
+			errMsg: "failed to decode yaml rule-set, unknown field post_eval at $.rules.R1.post_eval, line 6, column 5, " +
+				"expected number but found string at $.rules.R2.join_count, line 12, column 17, " +
+				"duplicate key when at $.relations[0].when, line 17, column 5",
+		},
+		{
+			name:    "strict decoding | yaml unquoted strings",
+			ruleSet: _unquotedYAMLRuleSet,
+			errMsg: "failed to decode yaml rule-set, expected string but found number at $.rules.R1.predicate, line 5, column 16, " +
+				"expected string but found bool at $.rules.R1.post_evals[0].value, line 9, column 16",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(test.ruleSet)), coffeemachine.WithStrictDecoding())
+			assert.Error(t, err)
+			assert.Equal(t, coffeemachine.ErrInvalidRuleSet, err.(*errors.Error).Code)
+			assert.Equal(t, test.errMsg, err.(*errors.Error).Msg)
+		})
+	}
+
+	t.Run("strict decoding | valid rule-sets", func(t *testing.T) {
+		for _, ruleSet := range []string{_simpleDependencyRuleSet, _simpleDependencyYAMLRuleSet, _constantsRuleSet, _schemaRuleSet} {
+			_, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(ruleSet)), coffeemachine.WithStrictDecoding())
+			assert.NoError(t, err)
+		}
+	})
+}
+
+func Test_EmptyRuleSet(t *testing.T) {
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_simpleDependencyYAMLRuleSet)))
+	assert.NoError(t, err)
+
+	for _, ruleSet := range []string{"", "   \n", "# no rules yet\n"} {
+		_, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(ruleSet)))
+		assert.Error(t, err)
+		assert.Equal(t, coffeemachine.ErrInvalidRuleSet, err.(*errors.Error).Code)
+		assert.Equal(t, "failed to decode yaml rule-set, empty rule-set", err.(*errors.Error).Msg)
+
+		_, err = coffeemachine.NewRuleEngine(bytes.NewReader([]byte(ruleSet)), coffeemachine.WithStrictDecoding())
+		assert.Error(t, err)
+		assert.Equal(t, coffeemachine.ErrInvalidRuleSet, err.(*errors.Error).Code)
+		assert.Equal(t, "failed to decode yaml rule-set, empty rule-set", err.(*errors.Error).Msg)
+
+		assert.Equal(t, []coffeemachine.Diagnostic{
+			{Severity: coffeemachine.SeverityError, Path: "$", Message: "failed to decode yaml rule-set, empty rule-set"},
+		}, coffeemachine.Lint(bytes.NewReader([]byte(ruleSet))))
+
+		err = engine.Reload(bytes.NewReader([]byte(ruleSet)))
+		assert.Error(t, err)
+		assert.Equal(t, coffeemachine.ErrInvalidRuleSet, err.(*errors.Error).Code)
+	}
+}
@@ -0,0 +1,69 @@
+package tests
+
+var _diamondRuleSet = `{
+  "id": "diamond_ruleset",
+  "predicates": {
+    "P1": "a > b",
+    "P2": "a + b > c"
+  },
+  "rules": {
+    "R1": {
+      "predicate": "Predicate:P1",
+      "post_evals": [
+        {
+          "id": "output_1",
+          "type": "CONST",
+          "value": "action_1"
+        }
+      ]
+    },
+    "R2": {
+      "predicate": "Predicate:P2",
+      "post_evals": [
+        {
+          "id": "output_1",
+          "type": "CONST",
+          "value": "action_2"
+        }
+      ]
+    },
+    "R3": {
+      "predicate": "Predicate:P2",
+      "post_evals": [
+        {
+          "id": "output_1",
+          "type": "CONST",
+          "value": "action_3"
+        }
+      ]
+    },
+    "R4": {
+      "predicate": "Predicate:P1",
+      "post_evals": [
+        {
+          "id": "output_1",
+          "type": "EXPR",
+          "value": "a + b + c"
+        }
+      ]
+    }
+  },
+  "relations": [
+    {
+      "from": "R1",
+      "to": "R2"
+    },
+    {
+      "from": "R1",
+      "to": "R3"
+    },
+    {
+      "from": "R2",
+      "to": "R4"
+    },
+    {
+      "from": "R3",
+      "to": "R4"
+    }
+  ]
+}`
@@ -0,0 +1,48 @@
+package tests
+
+var _forwardOutputRuleSet = `{
+  "id": "forward_output_ruleset",
+  "predicates": {
+    "P1": "a > b",
+    "P2": "R1.output_1 > c"
+  },
+  "rules": {
+    "R1": {
+      "predicate": "Predicate:P1",
+      "post_evals": [
+        {
+          "id": "output_1",
+          "type": "EXPR",
+          "value": "a + b"
+        },
+        {
+          "id": "output_2",
+          "type": "CONST",
+          "value": "action_1"
+        }
+      ]
+    },
+    "R2": {
+      "predicate": "Predicate:P2",
+      "post_evals": [
+        {
+          "id": "output_1",
+          "type": "EXPR",
+          "value": "R1.output_1 * 2"
+        },
+        {
+          "id": "output_2",
+          "type": "EXPR",
+          "value": "R1.output_2"
+        }
+      ]
+    }
+  },
+  "relations": [
+    {
+      "from": "R1",
+      "to": "R2",
+      "forward_output": true
+    }
+  ]
+}`
@@ -0,0 +1,323 @@
+package tests
+
+var _cyclicRuleSet = `{
+  "id": "cyclic_ruleset",
+  "predicates": {
+    "P1": "a > b"
+  },
+  "rules": {
+    "R1": {
+      "predicate": "Predicate:P1"
+    },
+    "R2": {
+      "predicate": "Predicate:P1"
+    },
+    "R3": {
+      "predicate": "Predicate:P1"
+    }
+  },
+  "relations": [
+    {
+      "from": "R1",
+      "to": "R2"
+    },
+    {
+      "from": "R2",
+      "to": "R3"
+    },
+    {
+      "from": "R3",
+      "to": "R2"
+    }
+  ]
+}`
+
+var _selfLoopRuleSet = `{
+  "id": "self_loop_ruleset",
+  "predicates": {
+    "P1": "a > b"
+  },
+  "rules": {
+    "R1": {
+      "predicate": "Predicate:P1"
+    }
+  },
+  "relations": [
+    {
+      "from": "R1",
+      "to": "R1"
+    }
+  ]
+}`
+
+var _duplicateRelationRuleSet = `{
+  "id": "duplicate_relation_ruleset",
+  "predicates": {
+    "P1": "a > b"
+  },
+  "rules": {
+    "R1": {
+      "predicate": "Predicate:P1"
+    },
+    "R2": {
+      "predicate": "Predicate:P1"
+    }
+  },
+  "relations": [
+    {
+      "from": "R1",
+      "to": "R2"
+    },
+    {
+      "from": "R1",
+      "to": "R2",
+      "forward_output": true
+    }
+  ]
+}`
+
+var _duplicateRuleIDRuleSet = `{
+  "id": "duplicate_rule_id_ruleset",
+  "predicates": {
+    "P1": "a > b"
+  },
+  "rules": {
+    "R1": {
+      "predicate": "Predicate:P1"
+    },
+    "R1": {
+      "predicate": "Predicate:P1"
+    }
+  }
+}`
+
+var _invalidJoinCountRuleSet = `{
+  "id": "invalid_join_count_ruleset",
+  "rules": {
+    "R1": {
+      "predicate": "a > b"
+    },
+    "R2": {
+      "predicate": "a > b",
+      "join": "n_of_m",
+      "join_count": 2
+    }
+  },
+  "relations": [
+    {
+      "from": "R1",
+      "to": "R2"
+    }
+  ]
+}`
+
+var _unusedJoinCountRuleSet = `{
+  "id": "unused_join_count_ruleset",
+  "rules": {
+    "R1": {
+      "predicate": "a > b"
+    },
+    "R2": {
+      "predicate": "a > b",
+      "join_count": 1
+    }
+  },
+  "relations": [
+    {
+      "from": "R1",
+      "to": "R2"
+    }
+  ]
+}`
+
+var _invalidJoinRuleSet = `{
+  "id": "invalid_join_ruleset",
+  "rules": {
+    "R1": {
+      "predicate": "a > b",
+      "join": "most"
+    }
+  }
+}`
+
+var _invalidConditionRuleSet = `{
+  "id": "invalid_condition_ruleset",
+  "rules": {
+    "R1": {
+      "predicate": "a > b"
+    },
+    "R2": {
+      "predicate": "a > b"
+    }
+  },
+  "relations": [
+    {
+      "from": "R1",
+      "to": "R2",
+      "condition": "a > > b"
+    }
+  ]
+}`
+
+var _invalidValidityRuleSet = `{
+  "id": "invalid_validity_ruleset",
+  "rules": {
+    "R1": {
+      "predicate": "a > b",
+      "valid_from": "2026-12-01T00:00:00Z",
+      "valid_until": "2026-11-01T00:00:00Z"
+    }
+  }
+}`
+
+var _cyclicPredicateRuleSet = `{
+  "id": "cyclic_predicate_ruleset",
+  "predicates": {
+    "P1": "a > b && Predicate:P2",
+    "P2": "b > c || Predicate:P3",
+    "P3": "Predicate:P1"
+  },
+  "rules": {
+    "R1": {
+      "predicate": "Predicate:P1"
+    }
+  }
+}`
+
+var _unknownPredicateRuleSet = `{
+  "id": "unknown_predicate_ruleset",
+  "predicates": {
+    "P1": "a > b"
+  },
+  "rules": {
+    "R1": {
+      "predicate": "(Predicate:P1 && Predicate:P9)"
+    }
+  }
+}`
+
+var _invalidConstantRuleSet = `{
+  "id": "invalid_constant_ruleset",
+  "constants": {
+    "LIMIT": {
+      "value": 1000
+    }
+  },
+  "rules": {
+    "R1": {
+      "predicate": "a > b"
+    }
+  }
+}`
+
+var _undefinedConstantRuleSet = `{
+  "id": "undefined_constant_ruleset",
+  "constants": {
+    "LIMIT": 1000
+  },
+  "rules": {
+    "R1": {
+      "predicate": "a > Constant:LIMITS"
+    }
+  }
+}`
+
+var _invalidSchemaTypeRuleSet = `{
+  "id": "invalid_schema_type_ruleset",
+  "schema": {
+    "amount": {
+      "type": "integer"
+    }
+  },
+  "rules": {
+    "R1": {
+      "predicate": "amount > 100"
+    }
+  }
+}`
+
+var _invalidSchemaDefaultRuleSet = `{
+  "id": "invalid_schema_default_ruleset",
+  "schema": {
+    "amount": {
+      "type": "number",
+      "default": "100"
+    }
+  },
+  "rules": {
+    "R1": {
+      "predicate": "amount > 100"
+    }
+  }
+}`
+
+var _illTypedPredicateRuleSet = `{
+  "id": "ill_typed_predicate_ruleset",
+  "predicates": {
+    "P1": "\"abc\" > 3"
+  },
+  "rules": {
+    "R1": {
+      "predicate": "Predicate:P1"
+    }
+  }
+}`
+
+var _nonBooleanPredicateRuleSet = `{
+  "id": "non_boolean_predicate_ruleset",
+  "schema": {
+    "amount": {
+      "type": "number"
+    }
+  },
+  "rules": {
+    "R1": {
+      "predicate": "amount * 2"
+    }
+  }
+}`
+
+var _illTypedSchemaRuleSet = `{
+  "id": "ill_typed_schema_ruleset",
+  "schema": {
+    "country": {
+      "type": "string"
+    }
+  },
+  "rules": {
+    "R1": {
+      "predicate": "a > b",
+      "post_evals": [
+        {
+          "id": "code",
+          "type": "EXPR",
+          "value": "country * 2"
+        }
+      ]
+    }
+  }
+}`
+
+var _exclusiveJoinRuleSet = `{
+  "id": "exclusive_join_ruleset",
+  "rules": {
+    "R1": {
+      "predicate": "a > b"
+    },
+    "R2": {
+      "predicate": "a > b",
+      "join": "all"
+    }
+  },
+  "relations": [
+    {
+      "from": "R1",
+      "to": "R2"
+    },
+    {
+      "from": "R1",
+      "to": "R2",
+      "when": false
+    }
+  ]
+}`
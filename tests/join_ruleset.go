@@ -0,0 +1,39 @@
+package tests
+
+var _joinRuleSet = `{
+  "id": "join_ruleset",
+  "rules": {
+    "R1": {
+      "predicate": "a > 0"
+    },
+    "R2": {
+      "predicate": "b > 0"
+    },
+    "R3": {
+      "predicate": "c > 0"
+    },
+    "ALL": {
+      "predicate": "true == true",
+      "join": "all"
+    },
+    "ANY": {
+      "predicate": "true == true"
+    },
+    "TWO": {
+      "predicate": "true == true",
+      "join": "n_of_m",
+      "join_count": 2
+    }
+  },
+  "relations": [
+    { "from": "R1", "to": "ALL" },
+    { "from": "R2", "to": "ALL" },
+    { "from": "R3", "to": "ALL" },
+    { "from": "R1", "to": "ANY" },
+    { "from": "R2", "to": "ANY" },
+    { "from": "R3", "to": "ANY" },
+    { "from": "R1", "to": "TWO" },
+    { "from": "R2", "to": "TWO" },
+    { "from": "R3", "to": "TWO" }
+  ]
+}`
@@ -0,0 +1,34 @@
+package tests
+
+import (
+	"fmt"
+	"strings"
+)
+
+// largeRuleSet generates a rule-set with the given number of independent
+// rules, all of them evaluate to true when a > b
+func largeRuleSet(size int) string {
+	rules := make([]string, 0, size)
+	for i := 0; i < size; i++ {
+		rules = append(rules, fmt.Sprintf(`"R%v": {
+      "predicate": "Predicate:P1",
+      "post_evals": [
+        {
+          "id": "output_1",
+          "type": "EXPR",
+          "value": "a + %v"
+        }
+      ]
+    }`, i, i))
+	}
+
+	return fmt.Sprintf(`{
+  "id": "large_ruleset",
+  "predicates": {
+    "P1": "a > b"
+  },
+  "rules": {
+    %v
+  }
+}`, strings.Join(rules, ",\n    "))
+}
@@ -0,0 +1,135 @@
+package tests
+
+var _lintRuleSet = `{
+  "id": "lint_ruleset",
+  "constants": {
+    "LIMIT": 1000,
+    "UNUSED_LIMIT": 10
+  },
+  "schema": {
+    "amount": {
+      "type": "number"
+    }
+  },
+  "predicates": {
+    "P1": "amount > Constant:LIMIT",
+    "P2": "Predicate:P1 && amount > 0",
+    "P3": "amount > 1",
+    "P4": "Predicate:P3"
+  },
+  "rules": {
+    "R1": {
+      "predicate": "Predicate:P2",
+      "post_evals": [
+        {
+          "id": "output_1",
+          "type": "EXPR",
+          "value": "amount * 2"
+        },
+        {
+          "id": "output_1",
+          "type": "CONST",
+          "value": "action_1"
+        }
+      ]
+    },
+    "R2": {
+      "predicate": "R1.output_1 > amt"
+    },
+    "R3": {
+      "predicate": "amount > 0",
+      "enabled": false
+    },
+    "R4": {
+      "predicate": "true",
+      "join": "all"
+    },
+    "R5": {
+      "predicate": "amount > 0"
+    },
+    "R6": {
+      "predicate": "1 == 1"
+    }
+  },
+  "relations": [
+    {
+      "from": "R1",
+      "to": "R2",
+      "forward_output": true
+    },
+    {
+      "from": "R3",
+      "to": "R4"
+    },
+    {
+      "from": "R1",
+      "to": "R4"
+    },
+    {
+      "from": "R6",
+      "to": "R5",
+      "when": false
+    }
+  ]
+}`
+
+var _cleanLintRuleSet = `
+id: clean_lint_ruleset
+predicates:
+  P1: amount > 100
+rules:
+  R1:
+    predicate: Predicate:P1
+    post_evals:
+      - id: score
+        type: EXPR
+        value: amount * 2
+  R2:
+    predicate: R1.score > limit
+relations:
+  - from: R1
+    to: R2
+    forward_output: true
+`
+
+var _invalidLintRuleSet = `{
+  "id": "invalid_lint_ruleset",
+  "rules": {
+    "R1": {},
+    "R2": {
+      "predicate": "amount >"
+    },
+    "R3": {
+      "predicate": "amount + 1",
+      "post_evals": [
+        {
+          "id": "output_1",
+          "type": "EXPR",
+          "value": "amount * "
+        }
+      ]
+    }
+  }
+}`
+
+var _unknownSourceLintRuleSet = `{
+  "id": "unknown_source_lint_ruleset",
+  "schema": {
+    "x": {
+      "type": "number"
+    }
+  },
+  "rules": {
+    "A": {
+      "predicate": "x > 1"
+    }
+  },
+  "relations": [
+    {
+      "from": "Z",
+      "to": "A",
+      "forward_output": true,
+      "condition": "x > 1"
+    }
+  ]
+}`
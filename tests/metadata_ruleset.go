@@ -0,0 +1,32 @@
+package tests
+
+var _metadataRuleSet = `{
+  "id": "metadata_ruleset",
+  "rules": {
+    "BASE": {
+      "description": "applies to every order",
+      "tags": ["pricing"],
+      "predicate": "amount > 0"
+    },
+    "PROMO": {
+      "description": "black friday promotion",
+      "tags": ["pricing", "promotion"],
+      "valid_from": "2026-11-01T00:00:00Z",
+      "valid_until": "2026-12-01T00:00:00Z",
+      "predicate": "amount > 0"
+    },
+    "DISABLED": {
+      "enabled": false,
+      "predicate": "amount > 0"
+    },
+    "CHILD": {
+      "predicate": "amount > 0"
+    }
+  },
+  "relations": [
+    {
+      "from": "DISABLED",
+      "to": "CHILD"
+    }
+  ]
+}`
@@ -0,0 +1,36 @@
+package tests
+
+// rules without an order default to 0, ties are broken by the order of
+// declaration and a rule is always executed after its parent
+var _orderedRuleSet = `{
+  "id": "ordered_ruleset",
+  "predicates": {
+    "P1": "a > b"
+  },
+  "rules": {
+    "Z": {
+      "predicate": "Predicate:P1"
+    },
+    "A": {
+      "predicate": "Predicate:P1",
+      "order": 2
+    },
+    "M": {
+      "predicate": "Predicate:P1"
+    },
+    "C": {
+      "predicate": "Predicate:P1",
+      "order": 1
+    },
+    "B": {
+      "predicate": "Predicate:P1",
+      "order": 5
+    }
+  },
+  "relations": [
+    {
+      "from": "M",
+      "to": "B"
+    }
+  ]
+}`
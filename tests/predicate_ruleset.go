@@ -0,0 +1,26 @@
+package tests
+
+var _composedPredicateRuleSet = `{
+  "id": "composed_predicate_ruleset",
+  "predicates": {
+    "is-adult": "age >= 18",
+    "RICH": "income > 1000",
+    "ELIGIBLE": "(Predicate:is-adult && Predicate:RICH)",
+    "VIP": "Predicate:ELIGIBLE && income > 5000"
+  },
+  "rules": {
+    "R1": {
+      "predicate": "Predicate:ELIGIBLE",
+      "post_evals": [
+        {
+          "id": "vip",
+          "type": "EXPR",
+          "value": "Predicate:VIP"
+        }
+      ]
+    },
+    "R2": {
+      "predicate": "Predicate:RICH && Predicate:VIP"
+    }
+  }
+}`
@@ -0,0 +1,38 @@
+package tests
+
+var _priorityRuleSet = `{
+  "id": "priority_ruleset",
+  "predicates": {
+    "P1": "amount > 100",
+    "P2": "amount > 1000"
+  },
+  "rules": {
+    "D1": {
+      "predicate": "Predicate:P1",
+      "priority": 1
+    },
+    "D2": {
+      "predicate": "Predicate:P1",
+      "priority": 5
+    },
+    "D3": {
+      "predicate": "Predicate:P1",
+      "priority": 5
+    },
+    "D4": {
+      "predicate": "Predicate:P1",
+      "priority": 3
+    },
+    "D5": {
+      "predicate": "Predicate:P2",
+      "priority": 10,
+      "on_false": [
+        {
+          "id": "small_amount",
+          "type": "EXPR",
+          "value": "true"
+        }
+      ]
+    }
+  }
+}`
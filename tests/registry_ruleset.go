@@ -0,0 +1,30 @@
+package tests
+
+var _registryRuleSet = `{
+  "id": "registry_ruleset",
+  "schema": {
+    "amount": {
+      "type": "number",
+      "required": true
+    },
+    "tier": {
+      "type": "string",
+      "default": "basic"
+    }
+  },
+  "predicates": {
+    "P1": "discount(amount, tier) > 50"
+  },
+  "rules": {
+    "R1": {
+      "predicate": "Predicate:P1 && tier ~= \"GOLD\"",
+      "post_evals": [
+        {
+          "id": "discount",
+          "type": "EXPR",
+          "value": "discount(amount, tier)"
+        }
+      ]
+    }
+  }
+}`
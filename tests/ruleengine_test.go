@@ -2,23 +2,20 @@ package tests
 
 import (
 	"bytes"
-	"sort"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	coffeemachine "github.com/anshal21/coffee-machine"
+	"github.com/anshal21/coffee-machine/expressions"
 	"github.com/anshal21/coffee-machine/lib"
+	"github.com/anshal21/coffee-machine/lib/errors"
 	"github.com/anshal21/coffee-machine/lib/models"
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_SampleRule(t *testing.T) {
-
-	sortSlice := func(outputs []*coffeemachine.RuleOutput) {
-		sort.Slice(outputs, func(i, j int) bool {
-			return outputs[i].ID < outputs[j].ID
-		})
-	}
-
 	tests := []struct {
 		name    string
 		ruleSet string
@@ -154,11 +151,1048 @@ func Test_SampleRule(t *testing.T) {
 			if test.err != nil {
 				assert.Error(t, err)
 			} else {
-				sortSlice(test.res.Outputs)
-				sortSlice(res.Outputs)
 				assert.Equal(t, test.res, res)
 			}
 		})
 	}
 
 }
+
+func Test_InvalidRuleSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		ruleSet string
+		errMsg  string
+	}{
+		{
+			name:    "invalid rule-set | cycle in relations",
+			ruleSet: _cyclicRuleSet,
+			errMsg:  "relations contain a cycle R2 -> R3 -> R2",
+		},
+		{
+			name:    "invalid rule-set | self-loop",
+			ruleSet: _selfLoopRuleSet,
+			errMsg:  "rule R1 has a relation to itself",
+		},
+		{
+			name:    "invalid rule-set | duplicate relation",
+			ruleSet: _duplicateRelationRuleSet,
+			errMsg:  "duplicate relation from R1 to R2",
+		},
+		{
+			name:    "invalid rule-set | join_count larger than relations",
+			ruleSet: _invalidJoinCountRuleSet,
+			errMsg:  "rule R2 has join_count 2, it should be between 1 and 1, the number of relations to the rule",
+		},
+		{
+			name:    "invalid rule-set | join_count without join n_of_m",
+			ruleSet: _unusedJoinCountRuleSet,
+			errMsg:  "rule R2 has join_count 1 with join any, join_count is only used with join n_of_m",
+		},
+		{
+			name:    "invalid rule-set | join all on both true and false",
+			ruleSet: _exclusiveJoinRuleSet,
+			errMsg:  "rule R2 has join all with relations on both true and false from the same rule, it can never be evaluated",
+		},
+		{
+			name:    "invalid rule-set | unknown join",
+			ruleSet: _invalidJoinRuleSet,
+			errMsg:  "rule R1 has invalid join most",
+		},
+		{
+			name:    "invalid rule-set | empty validity window",
+			ruleSet: _invalidValidityRuleSet,
+			errMsg:  "rule R1 has valid_from after valid_until",
+		},
+		{
+			name:    "invalid rule-set | duplicate rule id",
+			ruleSet: _duplicateRuleIDRuleSet,
+			errMsg:  "rule id R1 has been used already",
+		},
+		{
+			name:    "invalid rule-set | cyclic predicate references",
+			ruleSet: _cyclicPredicateRuleSet,
+			errMsg:  "rule R1 has invalid predicate, invalid reference Predicate:P1 at position 0, cyclic predicate reference P1 -> P2 -> P3 -> P1",
+		},
+		{
+			name:    "invalid rule-set | reference to unknown predicate",
+			ruleSet: _unknownPredicateRuleSet,
+			errMsg:  "rule R1 has invalid predicate, invalid reference Predicate:P9 at position 17, reference to invalid predicate P9",
+		},
+		{
+			name:    "invalid rule-set | constant of unsupported type",
+			ruleSet: _invalidConstantRuleSet,
+			errMsg:  "constant LIMIT has invalid value, unsupported constant type map[string]interface {}",
+		},
+		{
+			name:    "invalid rule-set | reference to undefined constant",
+			ruleSet: _undefinedConstantRuleSet,
+			errMsg:  "rule R1 has invalid predicate, invalid reference Constant:LIMITS at position 4, reference to undefined constant LIMITS",
+		},
+		{
+			name:    "invalid rule-set | unknown variable type",
+			ruleSet: _invalidSchemaTypeRuleSet,
+			errMsg:  "variable amount has invalid type integer",
+		},
+		{
+			name:    "invalid rule-set | default of a different type",
+			ruleSet: _invalidSchemaDefaultRuleSet,
+			errMsg:  "variable amount has default of type string, it should be number",
+		},
+		{
+			name:    "invalid rule-set | ill-typed predicate",
+			ruleSet: _illTypedPredicateRuleSet,
+			errMsg:  "rule R1 has invalid predicate, invalid reference Predicate:P1 at position 0, predicate P1 is invalid, cannot apply '>' operation on type 'string' and 'number' at position 6",
+		},
+		{
+			name:    "invalid rule-set | non-boolean predicate",
+			ruleSet: _nonBooleanPredicateRuleSet,
+			errMsg:  "rule R1 has a predicate of type number, it should be bool",
+		},
+		{
+			name:    "invalid rule-set | ill-typed post-eval on schema variable",
+			ruleSet: _illTypedSchemaRuleSet,
+			errMsg:  "rule R1 has invalid predicate for output code, cannot apply '*' operation on type 'string' and 'number' at position 8",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(test.ruleSet)))
+			assert.Error(t, err)
+			ruleEngineErr, ok := err.(*errors.Error)
+			assert.True(t, ok)
+			assert.Equal(t, coffeemachine.ErrInvalidRuleSet, ruleEngineErr.Code)
+			assert.Equal(t, test.errMsg, ruleEngineErr.Msg)
+		})
+	}
+}
+
+func Test_DiamondRuleSet(t *testing.T) {
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_diamondRuleSet)))
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		res, err := engine.Run(&coffeemachine.RuleEngineRequest{
+			Variables: map[string]interface{}{
+				"a": 10,
+				"b": 8,
+				"c": 6,
+			},
+		})
+		assert.NoError(t, err)
+
+		ruleIDs := outputRuleIDs(res.Outputs)
+		assert.Equal(t, []string{"R1", "R2", "R3", "R4"}, ruleIDs)
+	}
+}
+
+func Test_ForwardOutput(t *testing.T) {
+	tests := []struct {
+		name      string
+		variables map[string]interface{}
+		res       *coffeemachine.RuleOutput
+	}{
+		{
+			name: "forward_output | parent outputs are available to the child",
+			variables: map[string]interface{}{
+				"a": 10,
+				"b": 8,
+				"c": 12,
+			},
+			res: &coffeemachine.RuleOutput{
+				ID: "R2",
+				PostEvals: []*coffeemachine.EvaluationOutput{
+					&coffeemachine.EvaluationOutput{
+						ID:   "output_1",
+						Type: models.DataTypeNumber,
+						Value: models.Value{
+							Number: lib.Float64Ptr(36),
+						},
+					},
+					&coffeemachine.EvaluationOutput{
+						ID:   "output_2",
+						Type: models.DataTypeString,
+						Value: models.Value{
+							String: lib.StrPtr("action_1"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "forward_output | forwarded outputs take precedence over request variables",
+			variables: map[string]interface{}{
+				"a":           10,
+				"b":           8,
+				"c":           12,
+				"R1.output_1": 1,
+			},
+			res: &coffeemachine.RuleOutput{
+				ID: "R2",
+				PostEvals: []*coffeemachine.EvaluationOutput{
+					&coffeemachine.EvaluationOutput{
+						ID:   "output_1",
+						Type: models.DataTypeNumber,
+						Value: models.Value{
+							Number: lib.Float64Ptr(36),
+						},
+					},
+					&coffeemachine.EvaluationOutput{
+						ID:   "output_2",
+						Type: models.DataTypeString,
+						Value: models.Value{
+							String: lib.StrPtr("action_1"),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "forward_output | child evaluates to false on forwarded value",
+			variables: map[string]interface{}{
+				"a": 10,
+				"b": 8,
+				"c": 20,
+			},
+		},
+	}
+
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_forwardOutputRuleSet)))
+	assert.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := engine.Run(&coffeemachine.RuleEngineRequest{
+				Variables: test.variables,
+			})
+			assert.NoError(t, err)
+			if test.res == nil {
+				assert.Len(t, res.Outputs, 1)
+				return
+			}
+			assert.Len(t, res.Outputs, 2)
+			assert.Equal(t, test.res, res.Outputs[1])
+		})
+	}
+}
+
+func Test_EvaluationStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *coffeemachine.RuleEngineRequest
+		res     *coffeemachine.RuleEngineResponse
+	}{
+		{
+			name: "evaluation stats | all stats requested",
+			request: &coffeemachine.RuleEngineRequest{
+				Variables: map[string]interface{}{
+					"a": 8,
+					"b": 10,
+					"c": 6,
+				},
+				EvaluatedCount:     true,
+				EvaluatedTrueCount: true,
+				EvaluatedRules:     true,
+			},
+			res: &coffeemachine.RuleEngineResponse{
+				RulesEvaluated:      2,
+				RulesEvaluatedTrue:  1,
+				EvaluatedRules:      []string{"R1", "R3"},
+				EvaluatedFalseRules: []string{"R1"},
+			},
+		},
+		{
+			name: "evaluation stats | only counts requested",
+			request: &coffeemachine.RuleEngineRequest{
+				Variables: map[string]interface{}{
+					"a": 10,
+					"b": 8,
+					"c": 6,
+				},
+				EvaluatedCount:     true,
+				EvaluatedTrueCount: true,
+			},
+			res: &coffeemachine.RuleEngineResponse{
+				RulesEvaluated:     3,
+				RulesEvaluatedTrue: 3,
+			},
+		},
+		{
+			name: "evaluation stats | nothing requested",
+			request: &coffeemachine.RuleEngineRequest{
+				Variables: map[string]interface{}{
+					"a": 10,
+					"b": 8,
+					"c": 6,
+				},
+			},
+			res: &coffeemachine.RuleEngineResponse{},
+		},
+	}
+
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_simpleDependencyRuleSet)))
+	assert.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := engine.Run(test.request)
+			assert.NoError(t, err)
+			res.Outputs = nil
+			assert.Equal(t, test.res, res)
+		})
+	}
+}
+
+func Test_RunStream(t *testing.T) {
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(largeRuleSet(250))))
+	assert.NoError(t, err)
+
+	request := &coffeemachine.RuleEngineRequest{
+		Variables: map[string]interface{}{
+			"a": 10,
+			"b": 8,
+		},
+	}
+
+	t.Run("run | more than 100 outputs", func(t *testing.T) {
+		res, err := engine.Run(request)
+		assert.NoError(t, err)
+		assert.Len(t, res.Outputs, 250)
+	})
+
+	t.Run("run stream | all outputs", func(t *testing.T) {
+		count := 0
+		err := engine.RunStream(request, func(ruleOutput *coffeemachine.RuleOutput) error {
+			count++
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 250, count)
+	})
+
+	t.Run("run stream | early stop", func(t *testing.T) {
+		errStop := fmt.Errorf("stop")
+		count := 0
+		err := engine.RunStream(request, func(ruleOutput *coffeemachine.RuleOutput) error {
+			count++
+			if count == 5 {
+				return errStop
+			}
+			return nil
+		})
+		assert.Equal(t, errStop, err)
+		assert.Equal(t, 5, count)
+	})
+}
+
+func Test_OutputOrder(t *testing.T) {
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_orderedRuleSet)))
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		res, err := engine.Run(&coffeemachine.RuleEngineRequest{
+			Variables: map[string]interface{}{
+				"a": 10,
+				"b": 8,
+			},
+			EvaluatedRules: true,
+		})
+		assert.NoError(t, err)
+
+		ruleIDs := outputRuleIDs(res.Outputs)
+		assert.Equal(t, []string{"Z", "M", "C", "A", "B"}, ruleIDs)
+		assert.Equal(t, ruleIDs, res.EvaluatedRules)
+	}
+}
+
+func Test_Strategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy coffeemachine.Strategy
+		limit    int
+		ruleIDs  []string
+		err      error
+	}{
+		{
+			name:     "strategy | all matches",
+			strategy: coffeemachine.StrategyAllMatches,
+			ruleIDs:  []string{"D1", "D2", "D3", "D4", "D5"},
+		},
+		{
+			name:     "strategy | first match",
+			strategy: coffeemachine.StrategyFirstMatch,
+			ruleIDs:  []string{"D2"},
+		},
+		{
+			name:     "strategy | highest priority",
+			strategy: coffeemachine.StrategyHighestPriority,
+			ruleIDs:  []string{"D2", "D3"},
+		},
+		{
+			name:     "strategy | top n",
+			strategy: coffeemachine.StrategyTopN,
+			limit:    3,
+			ruleIDs:  []string{"D2", "D3", "D4"},
+		},
+		{
+			name:     "strategy | top n larger than matches",
+			strategy: coffeemachine.StrategyTopN,
+			limit:    10,
+			ruleIDs:  []string{"D2", "D3", "D4", "D1"},
+		},
+		{
+			name:     "strategy | top n without limit",
+			strategy: coffeemachine.StrategyTopN,
+			err:      fmt.Errorf("strategy top_n requires a positive limit, got 0"),
+		},
+	}
+
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_priorityRuleSet)))
+	assert.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := engine.Run(&coffeemachine.RuleEngineRequest{
+				Variables: map[string]interface{}{
+					"amount": 500,
+				},
+				Strategy: test.strategy,
+				Limit:    test.limit,
+			})
+			if test.err != nil {
+				assert.Error(t, err)
+				assert.Equal(t, coffeemachine.ErrInvalidRequest, err.(*errors.Error).Code)
+				assert.Equal(t, test.err.Error(), err.(*errors.Error).Msg)
+				return
+			}
+			assert.NoError(t, err)
+
+			ruleIDs := outputRuleIDs(res.Outputs)
+			assert.Equal(t, test.ruleIDs, ruleIDs)
+		})
+	}
+}
+
+func Test_OnFalse(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  int
+		outputs []*coffeemachine.RuleOutput
+	}{
+		{
+			name:   "on_false | true branch",
+			amount: 5000,
+			outputs: []*coffeemachine.RuleOutput{
+				&coffeemachine.RuleOutput{
+					ID: "R1",
+					PostEvals: []*coffeemachine.EvaluationOutput{
+						&coffeemachine.EvaluationOutput{
+							ID:   "tier",
+							Type: models.DataTypeString,
+							Value: models.Value{
+								String: lib.StrPtr("premium"),
+							},
+						},
+					},
+				},
+				&coffeemachine.RuleOutput{
+					ID: "R3",
+					PostEvals: []*coffeemachine.EvaluationOutput{
+						&coffeemachine.EvaluationOutput{
+							ID:   "discount",
+							Type: models.DataTypeNumber,
+							Value: models.Value{
+								Number: lib.Float64Ptr(1000),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:   "on_false | false branch",
+			amount: 500,
+			outputs: []*coffeemachine.RuleOutput{
+				&coffeemachine.RuleOutput{
+					ID: "R1",
+					PostEvals: []*coffeemachine.EvaluationOutput{
+						&coffeemachine.EvaluationOutput{
+							ID:   "tier",
+							Type: models.DataTypeString,
+							Value: models.Value{
+								String: lib.StrPtr("standard"),
+							},
+						},
+					},
+					OnFalse: true,
+				},
+				&coffeemachine.RuleOutput{
+					ID: "R2",
+					PostEvals: []*coffeemachine.EvaluationOutput{
+						&coffeemachine.EvaluationOutput{
+							ID:   "discount",
+							Type: models.DataTypeNumber,
+							Value: models.Value{
+								Number: lib.Float64Ptr(50),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:   "on_false | false branch without on_false post-evals",
+			amount: 50,
+			outputs: []*coffeemachine.RuleOutput{
+				&coffeemachine.RuleOutput{
+					ID: "R1",
+					PostEvals: []*coffeemachine.EvaluationOutput{
+						&coffeemachine.EvaluationOutput{
+							ID:   "tier",
+							Type: models.DataTypeString,
+							Value: models.Value{
+								String: lib.StrPtr("standard"),
+							},
+						},
+					},
+					OnFalse: true,
+				},
+			},
+		},
+	}
+
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_decisionTreeRuleSet)))
+	assert.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := engine.Run(&coffeemachine.RuleEngineRequest{
+				Variables: map[string]interface{}{
+					"amount": test.amount,
+				},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, test.outputs, res.Outputs)
+		})
+	}
+}
+
+func Test_Join(t *testing.T) {
+	tests := []struct {
+		name      string
+		variables map[string]interface{}
+		ruleIDs   []string
+	}{
+		{
+			name: "join | all parents true",
+			variables: map[string]interface{}{
+				"a": 1,
+				"b": 1,
+				"c": 1,
+			},
+			ruleIDs: []string{"R1", "R2", "R3", "ALL", "ANY", "TWO"},
+		},
+		{
+			name: "join | two parents true",
+			variables: map[string]interface{}{
+				"a": 1,
+				"b": 0,
+				"c": 1,
+			},
+			ruleIDs: []string{"R1", "R3", "ANY", "TWO"},
+		},
+		{
+			name: "join | one parent true",
+			variables: map[string]interface{}{
+				"a": 0,
+				"b": 1,
+				"c": 0,
+			},
+			ruleIDs: []string{"R2", "ANY"},
+		},
+		{
+			name: "join | no parent true",
+			variables: map[string]interface{}{
+				"a": 0,
+				"b": 0,
+				"c": 0,
+			},
+			ruleIDs: []string{},
+		},
+	}
+
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_joinRuleSet)))
+	assert.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := engine.Run(&coffeemachine.RuleEngineRequest{
+				Variables: test.variables,
+			})
+			assert.NoError(t, err)
+
+			ruleIDs := outputRuleIDs(res.Outputs)
+			assert.Equal(t, test.ruleIDs, ruleIDs)
+		})
+	}
+}
+
+func Test_ConditionalRelations(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  int
+		ruleIDs []string
+	}{
+		{
+			name:    "condition | condition on forwarded output holds",
+			amount:  600,
+			ruleIDs: []string{"ROUTER", "HIGH"},
+		},
+		{
+			name:    "condition | condition on request variable holds",
+			amount:  400,
+			ruleIDs: []string{"ROUTER", "LOW"},
+		},
+		{
+			name:    "condition | no condition holds",
+			amount:  -1,
+			ruleIDs: []string{},
+		},
+	}
+
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_conditionalRuleSet)))
+	assert.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := engine.Run(&coffeemachine.RuleEngineRequest{
+				Variables: map[string]interface{}{
+					"amount": test.amount,
+				},
+			})
+			assert.NoError(t, err)
+
+			ruleIDs := outputRuleIDs(res.Outputs)
+			assert.Equal(t, test.ruleIDs, ruleIDs)
+		})
+	}
+
+	_, err = coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_invalidConditionRuleSet)))
+	assert.Error(t, err)
+	assert.Equal(t, coffeemachine.ErrInvalidRuleSet, err.(*errors.Error).Code)
+	assert.Contains(t, err.(*errors.Error).Msg, "relation from R1 to R2 has invalid condition")
+}
+
+func Test_RuleMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		now     time.Time
+		ruleIDs []string
+	}{
+		{
+			name:    "metadata | before the validity window",
+			now:     time.Date(2026, 10, 31, 23, 59, 59, 0, time.UTC),
+			ruleIDs: []string{"BASE"},
+		},
+		{
+			name:    "metadata | in the validity window",
+			now:     time.Date(2026, 11, 15, 0, 0, 0, 0, time.UTC),
+			ruleIDs: []string{"BASE", "PROMO"},
+		},
+		{
+			name:    "metadata | at the end of the validity window",
+			now:     time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC),
+			ruleIDs: []string{"BASE"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			now := test.now
+			engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_metadataRuleSet)),
+				coffeemachine.WithClock(func() time.Time {
+					return now
+				}))
+			assert.NoError(t, err)
+
+			res, err := engine.Run(&coffeemachine.RuleEngineRequest{
+				Variables: map[string]interface{}{
+					"amount": 100,
+				},
+				EvaluatedRules: true,
+			})
+			assert.NoError(t, err)
+
+			ruleIDs := outputRuleIDs(res.Outputs)
+			assert.Equal(t, test.ruleIDs, ruleIDs)
+			assert.Equal(t, test.ruleIDs, res.EvaluatedRules)
+		})
+	}
+}
+
+func Test_RuleSelection(t *testing.T) {
+	tests := []struct {
+		name           string
+		request        *coffeemachine.RuleEngineRequest
+		ruleIDs        []string
+		evaluatedRules []string
+	}{
+		{
+			name:           "selection | no filters",
+			request:        &coffeemachine.RuleEngineRequest{},
+			ruleIDs:        []string{"FRAUD_CHECK", "FRAUD_BLOCK", "ELIGIBLE", "PRICING", "DISCOUNT"},
+			evaluatedRules: []string{"FRAUD_CHECK", "FRAUD_BLOCK", "ELIGIBLE", "PRICING", "DISCOUNT"},
+		},
+		{
+			name: "selection | include tag",
+			request: &coffeemachine.RuleEngineRequest{
+				IncludeTags: []string{"fraud"},
+			},
+			ruleIDs:        []string{"FRAUD_CHECK", "FRAUD_BLOCK"},
+			evaluatedRules: []string{"FRAUD_CHECK", "FRAUD_BLOCK"},
+		},
+		{
+			name: "selection | include rule evaluates its parents",
+			request: &coffeemachine.RuleEngineRequest{
+				IncludeRules: []string{"DISCOUNT"},
+			},
+			ruleIDs:        []string{"DISCOUNT"},
+			evaluatedRules: []string{"ELIGIBLE", "DISCOUNT"},
+		},
+		{
+			name: "selection | exclude tag",
+			request: &coffeemachine.RuleEngineRequest{
+				ExcludeTags: []string{"promotion", "fraud"},
+			},
+			ruleIDs:        []string{"ELIGIBLE", "PRICING"},
+			evaluatedRules: []string{"ELIGIBLE", "PRICING"},
+		},
+		{
+			name: "selection | exclusion wins over inclusion",
+			request: &coffeemachine.RuleEngineRequest{
+				IncludeTags:  []string{"pricing"},
+				ExcludeRules: []string{"DISCOUNT"},
+			},
+			ruleIDs:        []string{"PRICING"},
+			evaluatedRules: []string{"PRICING"},
+		},
+		{
+			name: "selection | excluded parent is still evaluated",
+			request: &coffeemachine.RuleEngineRequest{
+				IncludeTags:  []string{"fraud"},
+				ExcludeRules: []string{"FRAUD_CHECK"},
+			},
+			ruleIDs:        []string{"FRAUD_BLOCK"},
+			evaluatedRules: []string{"FRAUD_CHECK", "FRAUD_BLOCK"},
+		},
+	}
+
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_taggedRuleSet)))
+	assert.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.request.Variables = map[string]interface{}{
+				"amount": 100,
+			}
+			test.request.EvaluatedRules = true
+			res, err := engine.Run(test.request)
+			assert.NoError(t, err)
+
+			ruleIDs := outputRuleIDs(res.Outputs)
+			assert.Equal(t, test.ruleIDs, ruleIDs)
+			assert.Equal(t, test.evaluatedRules, res.EvaluatedRules)
+		})
+	}
+}
+
+func Test_PredicateReferences(t *testing.T) {
+	vip := func(value bool) []*coffeemachine.EvaluationOutput {
+		return []*coffeemachine.EvaluationOutput{
+			&coffeemachine.EvaluationOutput{
+				ID:   "vip",
+				Type: models.DataTypeBool,
+				Value: models.Value{
+					Bool: lib.BoolPtr(value),
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		age     int
+		income  int
+		outputs []*coffeemachine.RuleOutput
+	}{
+		{
+			name:   "predicate references | eligible",
+			age:    20,
+			income: 2000,
+			outputs: []*coffeemachine.RuleOutput{
+				&coffeemachine.RuleOutput{ID: "R1", PostEvals: vip(false)},
+			},
+		},
+		{
+			name:   "predicate references | vip",
+			age:    20,
+			income: 8000,
+			outputs: []*coffeemachine.RuleOutput{
+				&coffeemachine.RuleOutput{ID: "R1", PostEvals: vip(true)},
+				&coffeemachine.RuleOutput{ID: "R2", PostEvals: []*coffeemachine.EvaluationOutput{}},
+			},
+		},
+		{
+			name:   "predicate references | not eligible",
+			age:    16,
+			income: 8000,
+		},
+	}
+
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_composedPredicateRuleSet)))
+	assert.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := engine.Run(&coffeemachine.RuleEngineRequest{
+				Variables: map[string]interface{}{
+					"age":    test.age,
+					"income": test.income,
+				},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, test.outputs, res.Outputs)
+		})
+	}
+}
+
+func Test_Constants(t *testing.T) {
+	tests := []struct {
+		name      string
+		variables map[string]interface{}
+		ruleIDs   []string
+	}{
+		{
+			name: "constants | all constants match",
+			variables: map[string]interface{}{
+				"amount":   1500,
+				"country":  "IN",
+				"currency": "INR",
+			},
+			ruleIDs: []string{"R1", "R2"},
+		},
+		{
+			name: "constants | amount below the limit",
+			variables: map[string]interface{}{
+				"amount":   500,
+				"country":  "US",
+				"currency": "INR",
+			},
+			ruleIDs: []string{"R2"},
+		},
+		{
+			name: "constants | country not in the list",
+			variables: map[string]interface{}{
+				"amount":   1500,
+				"country":  "UK",
+				"currency": "GBP",
+			},
+			ruleIDs: []string{},
+		},
+	}
+
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_constantsRuleSet)))
+	assert.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := engine.Run(&coffeemachine.RuleEngineRequest{
+				Variables: test.variables,
+			})
+			assert.NoError(t, err)
+
+			for _, output := range res.Outputs {
+				if output.ID == "R1" {
+					assert.Equal(t, float64(2000), *output.PostEvals[0].Value.Number)
+				}
+			}
+			assert.Equal(t, test.ruleIDs, outputRuleIDs(res.Outputs))
+		})
+	}
+
+	graph, err := coffeemachine.NewParser().Parse(bytes.NewReader([]byte(_constantsYAMLRuleSet)))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"LIMIT":     float64(1000),
+		"COUNTRIES": []interface{}{"IN", "US"},
+	}, graph.Constants)
+}
+
+func Test_Schema(t *testing.T) {
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_schemaRuleSet)))
+	assert.NoError(t, err)
+
+	t.Run("schema | defaults are applied", func(t *testing.T) {
+		variables := map[string]interface{}{
+			"amount": 500,
+		}
+		res, err := engine.Run(&coffeemachine.RuleEngineRequest{
+			Variables: variables,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(res.Outputs))
+		assert.Equal(t, false, *res.Outputs[0].PostEvals[0].Value.Bool)
+		assert.Equal(t, map[string]interface{}{"amount": 500}, variables)
+	})
+
+	t.Run("schema | request values take precedence over defaults", func(t *testing.T) {
+		res, err := engine.Run(&coffeemachine.RuleEngineRequest{
+			Variables: map[string]interface{}{
+				"amount":  500,
+				"country": "IN",
+				"blocked": []string{"IN"},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, true, *res.Outputs[0].PostEvals[0].Value.Bool)
+	})
+
+	t.Run("schema | all problems are reported", func(t *testing.T) {
+		_, err := engine.Run(&coffeemachine.RuleEngineRequest{
+			Variables: map[string]interface{}{
+				"country": 91,
+				"premium": "yes",
+			},
+		})
+		assert.Error(t, err)
+		ruleEngineErr, ok := err.(*errors.Error)
+		assert.True(t, ok)
+		assert.Equal(t, coffeemachine.ErrInvalidVariables, ruleEngineErr.Code)
+		assert.Equal(t, "variables don't conform to the schema, variable amount is required, "+
+			"variable country should be of type string, found number, variable premium should be of type bool, found string", ruleEngineErr.Msg)
+		assert.Equal(t, []errors.Detail{
+			{Field: "amount", Msg: "variable amount is required"},
+			{Field: "country", Msg: "variable country should be of type string, found number"},
+			{Field: "premium", Msg: "variable premium should be of type bool, found string"},
+		}, ruleEngineErr.Details)
+	})
+}
+
+func Test_VariableTypes(t *testing.T) {
+	_, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_variableTypesRuleSet)))
+	assert.NoError(t, err)
+
+	_, err = coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_variableTypesRuleSet)), coffeemachine.WithVariableTypes(map[string]models.DataType{
+		"amount": models.DataTypeNumber,
+	}))
+	assert.Error(t, err)
+	assert.Equal(t, coffeemachine.ErrInvalidRuleSet, err.(*errors.Error).Code)
+	assert.Equal(t, "rule R1 has invalid predicate, cannot apply '>' operation on type 'number' and 'string' at position 7", err.(*errors.Error).Msg)
+}
+
+func Test_Lint(t *testing.T) {
+	diagnostics := coffeemachine.Lint(bytes.NewReader([]byte(_lintRuleSet)))
+	assert.Equal(t, []coffeemachine.Diagnostic{
+		{Severity: coffeemachine.SeverityWarning, Path: "$.predicates.P3", Message: "predicate P3 is never used"},
+		{Severity: coffeemachine.SeverityWarning, Path: "$.predicates.P4", Message: "predicate P4 is never used"},
+		{Severity: coffeemachine.SeverityWarning, Path: "$.constants.UNUSED_LIMIT", Message: "constant UNUSED_LIMIT is never used"},
+		{Severity: coffeemachine.SeverityError, RuleID: "R1", Path: "$.rules.R1.post_evals[1].id", Message: "rule R1 has more than one post_evals with id output_1"},
+		{Severity: coffeemachine.SeverityWarning, RuleID: "R4", Path: "$.rules.R4", Message: "rule R4 can never be evaluated, not enough of its relations can be followed"},
+		{Severity: coffeemachine.SeverityWarning, RuleID: "R5", Path: "$.rules.R5", Message: "rule R5 can never be evaluated, not enough of its relations can be followed"},
+		{Severity: coffeemachine.SeverityWarning, RuleID: "R3", Path: "$.relations[1]", Message: "relation from R3 to R4 can never be followed, rule R3 is disabled"},
+		{Severity: coffeemachine.SeverityWarning, RuleID: "R6", Path: "$.relations[3]", Message: "relation from R6 to R5 can never be followed, rule R6 always evaluates to true"},
+		{Severity: coffeemachine.SeverityError, RuleID: "R2", Path: "$.rules.R2.predicate", Message: "rule R2 uses undefined variable amt"},
+	}, diagnostics)
+
+	diagnostics = coffeemachine.Lint(bytes.NewReader([]byte(_cleanLintRuleSet)))
+	assert.Empty(t, diagnostics)
+
+	diagnostics = coffeemachine.Lint(bytes.NewReader([]byte(_cleanLintRuleSet)), coffeemachine.WithVariableTypes(map[string]models.DataType{
+		"amount": models.DataTypeNumber,
+	}))
+	assert.Equal(t, []coffeemachine.Diagnostic{
+		{Severity: coffeemachine.SeverityError, RuleID: "R2", Path: "$.rules.R2.predicate", Message: "rule R2 uses undefined variable limit"},
+	}, diagnostics)
+
+	diagnostics = coffeemachine.Lint(bytes.NewReader([]byte(_cyclicRuleSet)))
+	assert.Equal(t, []coffeemachine.Diagnostic{
+		{Severity: coffeemachine.SeverityError, Path: "$", Message: "relations contain a cycle R2 -> R3 -> R2"},
+	}, diagnostics)
+
+	diagnostics = coffeemachine.Lint(bytes.NewReader([]byte(_unknownSourceLintRuleSet)))
+	assert.Equal(t, []coffeemachine.Diagnostic{
+		{Severity: coffeemachine.SeverityError, Path: "$", Message: "invalid rule id Z used for relation"},
+	}, diagnostics)
+
+	diagnostics = coffeemachine.Lint(bytes.NewReader([]byte(_invalidLintRuleSet)))
+	assert.Equal(t, []coffeemachine.Diagnostic{
+		{Severity: coffeemachine.SeverityError, RuleID: "R1", Path: "$.rules.R1.predicate", Message: "rule R1 has invalid predicate, empty expression"},
+		{Severity: coffeemachine.SeverityError, RuleID: "R2", Path: "$.rules.R2.predicate", Message: "rule R2 has invalid predicate, missing operands for operator > at position 7"},
+		{Severity: coffeemachine.SeverityError, RuleID: "R3", Path: "$.rules.R3.predicate", Message: "rule R3 has a predicate of type number, it should be bool"},
+		{Severity: coffeemachine.SeverityError, RuleID: "R3", Path: "$.rules.R3.post_evals[0].value", Message: "rule R3 has invalid predicate for output output_1, missing operands for operator * at position 7"},
+	}, diagnostics)
+}
+
+func Test_Registry(t *testing.T) {
+	registry := expressions.NewRegistry()
+	assert.NoError(t, registry.RegisterFunction(expressions.FunctionDefinition{
+		Name:   "discount",
+		Params: []models.DataType{models.DataTypeNumber, models.DataTypeString},
+		Result: models.DataTypeNumber,
+		Call: func(args []models.Value) (models.Value, error) {
+			rate := 0.05
+			if *args[1].String == "gold" {
+				rate = 0.2
+			}
+			return models.Value{Number: lib.Float64Ptr(*args[0].Number * rate)}, nil
+		},
+	}))
+	assert.NoError(t, registry.RegisterOperator(expressions.OperatorDefinition{
+		Token:      "~=",
+		Precedence: 2,
+		Left:       models.DataTypeString,
+		Right:      models.DataTypeString,
+		Result:     models.DataTypeBool,
+		Apply: func(left, right models.Value) (models.Value, error) {
+			return models.Value{Bool: lib.BoolPtr(strings.EqualFold(*left.String, *right.String))}, nil
+		},
+	}))
+
+	_, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_registryRuleSet)))
+	assert.Error(t, err)
+	assert.Equal(t, "rule R1 has invalid predicate for output discount, unknown function discount at position 0", err.(*errors.Error).Msg)
+
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_registryRuleSet)), coffeemachine.WithRegistry(registry))
+	assert.NoError(t, err)
+
+	res, err := engine.Run(&coffeemachine.RuleEngineRequest{
+		Variables: map[string]interface{}{
+			"amount": 500,
+			"tier":   "gold",
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(res.Outputs))
+	assert.Equal(t, float64(100), *res.Outputs[0].PostEvals[0].Value.Number)
+
+	res, err = engine.Run(&coffeemachine.RuleEngineRequest{
+		Variables: map[string]interface{}{
+			"amount": 500,
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(res.Outputs))
+
+	diagnostics := coffeemachine.Lint(bytes.NewReader([]byte(_registryRuleSet)), coffeemachine.WithRegistry(registry))
+	assert.Empty(t, diagnostics)
+}
+
+// outputRuleIDs returns the ids of the rules of the outputs, in order
+func outputRuleIDs(outputs []*coffeemachine.RuleOutput) []string {
+	ruleIDs := make([]string, 0, len(outputs))
+	for _, output := range outputs {
+		ruleIDs = append(ruleIDs, output.ID)
+	}
+	return ruleIDs
+}
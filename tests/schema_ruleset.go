@@ -0,0 +1,43 @@
+package tests
+
+var _schemaRuleSet = `{
+  "id": "schema_ruleset",
+  "schema": {
+    "amount": {
+      "type": "number",
+      "required": true
+    },
+    "country": {
+      "type": "string",
+      "default": "IN"
+    },
+    "blocked": {
+      "type": "list",
+      "default": ["US"]
+    },
+    "premium": {
+      "type": "bool"
+    }
+  },
+  "rules": {
+    "R1": {
+      "predicate": "amount > 100 && country == \"IN\"",
+      "post_evals": [
+        {
+          "id": "blocked",
+          "type": "EXPR",
+          "value": "country in blocked"
+        }
+      ]
+    }
+  }
+}`
+
+var _variableTypesRuleSet = `{
+  "id": "variable_types_ruleset",
+  "rules": {
+    "R1": {
+      "predicate": "amount > \"100\""
+    }
+  }
+}`
@@ -0,0 +1,72 @@
+package tests
+
+var _misconfiguredRuleSet = `{
+  "id": "misconfigured_ruleset",
+  "predicates": {
+    "P1": "a > b",
+    "P1": "a < b"
+  },
+  "rules": {
+    "R1": {
+      "predicate": "Predicate:P1",
+      "post_eval": [
+        {
+          "id": "output_1",
+          "type": "CONST",
+          "value": "action_1"
+        }
+      ],
+      "order": "first"
+    },
+    "R1": {
+      "predicate": "Predicate:P1",
+      "priority": 1.5
+    }
+  },
+  "relations": [
+    {
+      "from": "R1",
+      "to": "R1",
+      "when": "yes"
+    }
+  ]
+}`
+
+var _misconfiguredYAMLRuleSet = `
+id: misconfigured_ruleset
+rules:
+  R1:
+    predicate: a > b
+    post_eval:
+      - id: output_1
+        type: CONST
+        value: action_1
+  R2:
+    predicate: a < b
+    join_count: many
+relations:
+  - from: R1
+    to: R2
+    when: true
+    when: false
+`
+
+var _unquotedYAMLRuleSet = `
+id: unquoted_ruleset
+rules:
+  R1:
+    predicate: 5
+    post_evals:
+      - id: output_1
+        type: CONST
+        value: true
+  R2:
+    predicate: a < b
+    post_evals:
+      - id: output_1
+        type: CONST
+        value: "true"
+      - id: output_2
+        type: CONST
+        value: !!str 10
+`
@@ -0,0 +1,37 @@
+package tests
+
+var _taggedRuleSet = `{
+  "id": "tagged_ruleset",
+  "rules": {
+    "FRAUD_CHECK": {
+      "tags": ["fraud"],
+      "predicate": "amount > 0"
+    },
+    "FRAUD_BLOCK": {
+      "tags": ["fraud"],
+      "predicate": "amount > 0"
+    },
+    "ELIGIBLE": {
+      "tags": ["eligibility"],
+      "predicate": "amount > 0"
+    },
+    "PRICING": {
+      "tags": ["pricing"],
+      "predicate": "amount > 0"
+    },
+    "DISCOUNT": {
+      "tags": ["pricing", "promotion"],
+      "predicate": "amount > 0"
+    }
+  },
+  "relations": [
+    {
+      "from": "FRAUD_CHECK",
+      "to": "FRAUD_BLOCK"
+    },
+    {
+      "from": "ELIGIBLE",
+      "to": "DISCOUNT"
+    }
+  ]
+}`
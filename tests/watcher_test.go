@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	coffeemachine "github.com/anshal21/coffee-machine"
+	"github.com/anshal21/coffee-machine/lib/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Watcher(t *testing.T) {
+	loader := coffeemachine.NewMemoryLoader([]byte(_simpleRuleSet))
+	engine, err := coffeemachine.NewRuleEngineFromLoader(loader)
+	assert.NoError(t, err)
+
+	events := make(chan *coffeemachine.ReloadEvent, 1)
+	watcher := coffeemachine.NewWatcher(engine, loader,
+		coffeemachine.WithPollInterval(5*time.Millisecond),
+		coffeemachine.WithReloadHandler(func(event *coffeemachine.ReloadEvent) {
+			events <- event
+		}),
+	)
+	watcher.Start()
+	defer watcher.Stop()
+
+	request := &coffeemachine.RuleEngineRequest{
+		Variables: map[string]interface{}{
+			"a": 10,
+			"b": 8,
+			"c": 6,
+		},
+	}
+
+	res, err := engine.Run(request)
+	assert.NoError(t, err)
+	assert.Len(t, res.Outputs, 1)
+
+	t.Run("watcher | valid rule-set is reloaded", func(t *testing.T) {
+		loader.Update([]byte(_simpleDependencyRuleSet))
+		event := waitForEvent(t, events)
+		assert.NoError(t, event.Err)
+		assert.Equal(t, "1", event.Version)
+
+		res, err := engine.Run(request)
+		assert.NoError(t, err)
+		assert.Len(t, res.Outputs, 3)
+	})
+
+	t.Run("watcher | invalid rule-set keeps the previous rule-graph", func(t *testing.T) {
+		loader.Update([]byte(_cyclicRuleSet))
+		event := waitForEvent(t, events)
+		assert.Error(t, event.Err)
+		assert.Equal(t, "2", event.Version)
+
+		res, err := engine.Run(request)
+		assert.NoError(t, err)
+		assert.Len(t, res.Outputs, 3)
+	})
+}
+
+func Test_DirectoryLoader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coffee-machine")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	loader := coffeemachine.NewDirectoryLoader(dir, "ruleset-*.json")
+
+	_, err = loader.Load()
+	assert.Error(t, err)
+	assert.Equal(t, coffeemachine.ErrRuleSetNotFound, err.(*errors.Error).Code)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ruleset-1.json"), []byte(_simpleRuleSet), 0644))
+	version1, err := loader.Version()
+	assert.NoError(t, err)
+
+	engine, err := coffeemachine.NewRuleEngineFromLoader(loader)
+	assert.NoError(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ruleset-2.json"), []byte(_simpleDependencyRuleSet), 0644))
+	version2, err := loader.Version()
+	assert.NoError(t, err)
+	assert.NotEqual(t, version1, version2)
+
+	ruleSet, err := loader.Load()
+	assert.NoError(t, err)
+	assert.NoError(t, engine.Reload(ruleSet))
+
+	res, err := engine.Run(&coffeemachine.RuleEngineRequest{
+		Variables: map[string]interface{}{
+			"a": 10,
+			"b": 8,
+			"c": 6,
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, res.Outputs, 3)
+
+	t.Run("directory loader | numbers are compared by value", func(t *testing.T) {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ruleset-10.json"), []byte(_simpleRuleSet), 0644))
+		version, err := loader.Version()
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(version, "ruleset-10.json:"))
+	})
+}
+
+func Test_WatcherStart(t *testing.T) {
+	loader := coffeemachine.NewMemoryLoader([]byte(_simpleRuleSet))
+	engine, err := coffeemachine.NewRuleEngineFromLoader(loader)
+	assert.NoError(t, err)
+
+	// the change made before Start is applied on the first poll
+	loader.Update([]byte(_simpleDependencyRuleSet))
+
+	events := make(chan *coffeemachine.ReloadEvent, 10)
+	watcher := coffeemachine.NewWatcher(engine, loader,
+		coffeemachine.WithPollInterval(5*time.Millisecond),
+		coffeemachine.WithReloadHandler(func(event *coffeemachine.ReloadEvent) {
+			events <- event
+		}),
+	)
+	watcher.Start()
+	watcher.Start()
+	defer watcher.Stop()
+
+	event := waitForEvent(t, events)
+	assert.NoError(t, event.Err)
+	assert.Equal(t, "1", event.Version)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, events, 0)
+}
+
+type failingLoader struct{}
+
+func (f *failingLoader) Load() (io.Reader, error) {
+	return nil, fmt.Errorf("loader is down")
+}
+
+func (f *failingLoader) Version() (string, error) {
+	return "", fmt.Errorf("loader is down")
+}
+
+func Test_WatcherFailure(t *testing.T) {
+	engine, err := coffeemachine.NewRuleEngine(bytes.NewReader([]byte(_simpleRuleSet)))
+	assert.NoError(t, err)
+
+	events := make(chan *coffeemachine.ReloadEvent, 10)
+	watcher := coffeemachine.NewWatcher(engine, &failingLoader{},
+		coffeemachine.WithPollInterval(5*time.Millisecond),
+		coffeemachine.WithReloadHandler(func(event *coffeemachine.ReloadEvent) {
+			events <- event
+		}),
+	)
+	watcher.Start()
+	defer watcher.Stop()
+
+	event := waitForEvent(t, events)
+	assert.EqualError(t, event.Err, "loader is down")
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, events, 0)
+}
+
+func waitForEvent(t *testing.T, events <-chan *coffeemachine.ReloadEvent) *coffeemachine.ReloadEvent {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the reload event")
+		return nil
+	}
+}
@@ -0,0 +1,160 @@
+package coffeemachine
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	_defaultPollInterval = 10 * time.Second
+)
+
+// Watcher is an interface to a watcher that keeps a RuleEngine in
+// sync with the rule-set provided by a Loader
+// It polls the loader for a new version of the rule-set and reloads
+// the engine whenever the version changes
+type Watcher interface {
+	// Start starts watching the loader for changes in the background
+	Start()
+	// Stop stops the watcher, it blocks till the background routine exits
+	Stop()
+}
+
+// ReloadEvent is a struct to report the result of a reload attempt
+// Err is nil if the engine was reloaded successfully, otherwise the
+// engine keeps running with the previous rule-set
+type ReloadEvent struct {
+	Version string
+	Err     error
+	Time    time.Time
+}
+
+// WatcherOption represent an option type to override default watcher attributes
+type WatcherOption func(w *watcher)
+
+// WithPollInterval sets the interval at which the loader is polled for changes
+func WithPollInterval(interval time.Duration) WatcherOption {
+	return func(w *watcher) {
+		w.interval = interval
+	}
+}
+
+// WithReloadHandler sets a callback that is notified of every reload attempt
+func WithReloadHandler(handler func(event *ReloadEvent)) WatcherOption {
+	return func(w *watcher) {
+		w.handler = handler
+	}
+}
+
+// NewWatcher returns a new Watcher for the engine and loader, with default or
+// provided options
+// The engine is considered to be running with the version it was loaded with
+// if it was created with NewRuleEngineFromLoader, otherwise with the version
+// of the rule-set available at the time of Start
+func NewWatcher(engine RuleEngine, loader Loader, options ...WatcherOption) Watcher {
+	w := &watcher{
+		engine:   engine,
+		loader:   loader,
+		interval: _defaultPollInterval,
+		handler:  func(event *ReloadEvent) {},
+		stopChan: make(chan struct{}),
+	}
+	for _, option := range options {
+		option(w)
+	}
+	return w
+}
+
+type watcher struct {
+	engine   RuleEngine
+	loader   Loader
+	interval time.Duration
+	handler  func(event *ReloadEvent)
+	version  string
+	// failure is the last error reported while reading the version, the
+	// same error is reported only once
+	failure   string
+	wg        sync.WaitGroup
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stopChan  chan struct{}
+}
+
+// Start starts polling the loader, the calls after the first one are no-op
+func (w *watcher) Start() {
+	w.startOnce.Do(w.start)
+}
+
+func (w *watcher) start() {
+	if engine, ok := w.engine.(*ruleengine); ok && engine.loaded != "" {
+		w.version = engine.loaded
+	} else {
+		version, err := w.loader.Version()
+		if err != nil {
+			w.reportFailure(err)
+		}
+		w.version = version
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.poll()
+			case <-w.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+func (w *watcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopChan)
+	})
+	w.wg.Wait()
+}
+
+// poll reloads the engine if the loader has a new version of the rule-set
+// A version that fails to load is not retried till the version changes again
+func (w *watcher) poll() {
+	version, err := w.loader.Version()
+	if err != nil {
+		w.reportFailure(err)
+		return
+	}
+	w.failure = ""
+	if version == w.version {
+		return
+	}
+	w.version = version
+
+	ruleSet, err := w.loader.Load()
+	if err != nil {
+		w.notify(version, err)
+		return
+	}
+	w.notify(version, w.engine.Reload(ruleSet))
+}
+
+// reportFailure notifies an error hit while reading the version, unless
+// it's the same as the last one
+func (w *watcher) reportFailure(err error) {
+	if err.Error() == w.failure {
+		return
+	}
+	w.failure = err.Error()
+	w.notify("", err)
+}
+
+func (w *watcher) notify(version string, err error) {
+	w.handler(&ReloadEvent{
+		Version: version,
+		Err:     err,
+		Time:    time.Now(),
+	})
+}